@@ -0,0 +1,175 @@
+package ggservice
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const listenFdsStart = 3 // first file descriptor passed by systemd (SD_LISTEN_FDS_START)
+
+// activatedSocket is a socket passed to the program by systemd socket activation.
+type activatedSocket struct {
+	name       string
+	listener   net.Listener
+	packetConn net.PacketConn
+}
+
+// inheritableSocket is a socket handed out by Listen or ListenPacket, which is passed on to the new process by an upgrade.
+type inheritableSocket struct {
+	name   string
+	socket interface{ File() (*os.File, error) }
+}
+
+var activation struct {
+	once      sync.Once
+	mutex     sync.Mutex
+	sockets   []*activatedSocket // sockets are removed once they have been handed out
+	inherited []inheritableSocket
+}
+
+// activatedSockets parses LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES once and returns the sockets that have not been handed out yet.
+// The environment variables are unset afterward, so they are not inherited by child processes. (see sd_listen_fds(3))
+func activatedSockets() []*activatedSocket {
+	activation.once.Do(func() {
+		defer func() {
+			_ = os.Unsetenv("LISTEN_PID")
+			_ = os.Unsetenv("LISTEN_FDS")
+			_ = os.Unsetenv("LISTEN_FDNAMES")
+			_ = os.Unsetenv(upgradeParentPidEnv)
+		}()
+
+		pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		if err != nil {
+			// an upgrading parent process can not know our pid beforehand, so it sets its own pid instead (see Upgrader)
+			parentPid, err := strconv.Atoi(os.Getenv(upgradeParentPidEnv))
+			if err != nil || parentPid != os.Getppid() {
+				return
+			}
+		} else if pid != os.Getpid() {
+			return
+		}
+		fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil || fds <= 0 {
+			return
+		}
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+		for i := 0; i < fds; i++ {
+			name := "unknown" // the name systemd uses when no FileDescriptorName= is configured
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+
+			// net duplicates the file descriptor, so the original is closed when the socket has been created
+			file := os.NewFile(uintptr(listenFdsStart+i), name)
+			socket := &activatedSocket{name: name}
+			if listener, err := net.FileListener(file); err == nil {
+				socket.listener = listener
+			} else if packetConn, err := net.FilePacketConn(file); err == nil {
+				socket.packetConn = packetConn
+			}
+			_ = file.Close()
+			if socket.listener != nil || socket.packetConn != nil {
+				activation.sockets = append(activation.sockets, socket)
+			}
+		}
+	})
+
+	activation.mutex.Lock()
+	defer activation.mutex.Unlock()
+	return activation.sockets
+}
+
+// takeActivatedSocket removes and returns the first activated socket with the given name matching the filter, or nil.
+func takeActivatedSocket(name string, filter func(socket *activatedSocket) bool) *activatedSocket {
+	activatedSockets()
+
+	activation.mutex.Lock()
+	defer activation.mutex.Unlock()
+	for i, socket := range activation.sockets {
+		if socket.name == name && filter(socket) {
+			activation.sockets = append(activation.sockets[:i], activation.sockets[i+1:]...)
+			return socket
+		}
+	}
+	return nil
+}
+
+// inherit remembers a socket handed out by Listen or ListenPacket, so an upgrade can pass it on to the new process.
+func inherit(name string, socket any) {
+	file, ok := socket.(interface{ File() (*os.File, error) })
+	if !ok {
+		return
+	}
+	activation.mutex.Lock()
+	defer activation.mutex.Unlock()
+	activation.inherited = append(activation.inherited, inheritableSocket{name: name, socket: file})
+}
+
+// inheritableFiles returns duplicates of the file descriptors of all open sockets handed out by Listen or ListenPacket, and their names.
+func inheritableFiles() ([]*os.File, []string) {
+	activation.mutex.Lock()
+	defer activation.mutex.Unlock()
+
+	var files []*os.File
+	var names []string
+	for _, inherited := range activation.inherited {
+		file, err := inherited.socket.File()
+		if err != nil {
+			continue // the socket has been closed
+		}
+		files = append(files, file)
+		names = append(names, inherited.name)
+	}
+	return files, names
+}
+
+// IsSocketActivated reports whether sockets were passed to the program by systemd socket activation.
+func IsSocketActivated() bool {
+	activatedSockets()
+
+	activation.mutex.Lock()
+	defer activation.mutex.Unlock()
+	return len(activation.sockets) > 0
+}
+
+// Listen returns the socket activated listener with the given name (FileDescriptorName= in the systemd .socket unit).
+// When the program is not socket activated, or no such listener was passed, it falls back to net.Listen(network, address).
+// Each activated listener is only returned once. The listener is passed on to the new process by an Upgrader.
+func Listen(name string, network string, address string) (net.Listener, error) {
+	socket := takeActivatedSocket(name, func(socket *activatedSocket) bool {
+		return socket.listener != nil
+	})
+	if socket != nil {
+		inherit(name, socket.listener)
+		return socket.listener, nil
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	inherit(name, listener)
+	return listener, nil
+}
+
+// ListenPacket returns the socket activated packet connection with the given name (FileDescriptorName= in the systemd .socket unit).
+// When the program is not socket activated, or no such connection was passed, it falls back to net.ListenPacket(network, address).
+// Each activated connection is only returned once. The connection is passed on to the new process by an Upgrader.
+func ListenPacket(name string, network string, address string) (net.PacketConn, error) {
+	socket := takeActivatedSocket(name, func(socket *activatedSocket) bool {
+		return socket.packetConn != nil
+	})
+	if socket != nil {
+		inherit(name, socket.packetConn)
+		return socket.packetConn, nil
+	}
+	packetConn, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	inherit(name, packetConn)
+	return packetConn, nil
+}
@@ -0,0 +1,73 @@
+package ggservice_test
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestListen(t *testing.T) {
+	t.Run("Without socket activation", func(t *testing.T) {
+		listener, err := ggservice.Listen("http", "tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = listener.Close()
+
+		packetConn, err := ggservice.ListenPacket("dns", "udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = packetConn.Close()
+	})
+
+	t.Run("With socket activation", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		file, err := listener.(*net.TCPListener).File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		// the helper process is started like systemd starts a socket activated service, with the socket as fd 3
+		command := exec.Command(os.Args[0], "-test.run=TestListenHelperProcess")
+		command.ExtraFiles = []*os.File{file}
+		command.Env = append(os.Environ(), "GGSERVICE_TEST_HELPER=1", "LISTEN_FDS=1", "LISTEN_FDNAMES=http", "EXPECTED_ADDRESS="+listener.Addr().String())
+		output, err := command.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%v: %s", err, output)
+		}
+	})
+}
+
+// TestListenHelperProcess is run as a separate process by TestListen.
+func TestListenHelperProcess(t *testing.T) {
+	if os.Getenv("GGSERVICE_TEST_HELPER") != "1" {
+		t.Skip("only run as a helper process")
+	}
+	// LISTEN_PID can only be set by the started process itself when started by the test
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	if !ggservice.IsSocketActivated() {
+		t.Fatal("expected to be socket activated")
+	}
+	listener, err := ggservice.Listen("http", "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if listener.Addr().String() != os.Getenv("EXPECTED_ADDRESS") {
+		t.Errorf("expected activated listener on %s, got %s", os.Getenv("EXPECTED_ADDRESS"), listener.Addr())
+	}
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Error("expected LISTEN_FDS to be unset")
+	}
+}
@@ -0,0 +1,66 @@
+package ggservice
+
+import "time"
+
+// Clock creates the timers of a service: the run sleep duration, the graceful shutdown time and the shutdown hook timeout.
+// It can be replaced by a fake clock in tests (see ggservicetest.FakeClock).
+type Clock interface {
+	Now() time.Time
+	NewTimer(duration time.Duration) Timer
+}
+
+// Timer is a timer created by a Clock, like time.Timer.
+type Timer interface {
+	C() <-chan time.Time // Receives the time when the timer fires
+	Stop() bool          // Stops the timer, and reports whether it was stopped before it fired
+}
+
+// realClock is the clock of the time package, used by default.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(duration time.Duration) Timer {
+	return realTimer{time.NewTimer(duration)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (s *Service) GetClock() Clock {
+	if s.clock == nil {
+		return realClock{}
+	}
+	return s.clock
+}
+
+// SetClock sets the clock the timers of the service are created with, nil for the clock of the time package. (default: nil)
+// It can not be changed while the service is started.
+func (s *Service) SetClock(clock Clock) error {
+	err := s.errIfStarted("the clock")
+	if err != nil {
+		return err
+	}
+	s.clock = clock
+	return nil
+}
+
+// clockOf returns the clock of the service, or the clock of the time package if the service has no clock.
+func clockOf(service IService) Clock {
+	clocked, ok := service.(interface{ GetClock() Clock })
+	if !ok {
+		return realClock{}
+	}
+	return clocked.GetClock()
+}
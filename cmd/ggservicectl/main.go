@@ -0,0 +1,152 @@
+// Command ggservicectl talks to the control listener of a program using ggservice (see ggservice.Control).
+//
+// Usage:
+//
+//	ggservicectl [-socket path] [-o table|json] status
+//	ggservicectl [-socket path] [-o table|json] restart <service name>
+//	ggservicectl [-socket path] [-o table|json] logs [-n lines] [--follow]
+//
+// Exit codes: 0 on success, 1 if the command failed, 2 on wrong usage and 3 if the control socket could not be reached.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+// Exit codes
+const (
+	EXIT_OK          = 0
+	EXIT_FAILED      = 1
+	EXIT_USAGE       = 2
+	EXIT_UNAVAILABLE = 3
+)
+
+const dialTimeout = 5 * time.Second
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes ggservicectl with the given arguments and returns the exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ggservicectl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	socketPath := flags.String("socket", "/run/ggservice.sock", "path of the control socket")
+	output := flags.String("o", "table", "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: ggservicectl [-socket path] [-o table|json] status | restart <service name> | logs [-n lines] [--follow]")
+		flags.PrintDefaults()
+	}
+	if flags.Parse(args) != nil {
+		return EXIT_USAGE
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintln(stderr, "unknown output format: "+*output)
+		return EXIT_USAGE
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	request, ok := parseCommand(flags.Args(), stderr)
+	if !ok {
+		return EXIT_USAGE
+	}
+
+	conn, err := net.DialTimeout("unix", *socketPath, dialTimeout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_UNAVAILABLE
+	}
+	defer conn.Close()
+
+	err = json.NewEncoder(conn).Encode(request)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_UNAVAILABLE
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var response ggservice.ControlResponse
+		err := json.Unmarshal(scanner.Bytes(), &response)
+		if err != nil {
+			fmt.Fprintln(stderr, "invalid response: "+err.Error())
+			return EXIT_FAILED
+		}
+		if response.Error != "" {
+			if *output == "json" {
+				fmt.Fprintln(stdout, scanner.Text())
+			}
+			fmt.Fprintln(stderr, response.Error)
+			return EXIT_FAILED
+		}
+		printResponse(stdout, *output, request.Command, scanner.Text(), response)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_FAILED
+	}
+	return EXIT_OK
+}
+
+// parseCommand turns the command line arguments after the global flags into a control request.
+func parseCommand(args []string, stderr io.Writer) (ggservice.ControlRequest, bool) {
+	request := ggservice.ControlRequest{Command: args[0]}
+	switch request.Command {
+	case ggservice.CONTROL_COMMAND_STATUS:
+		if len(args) != 1 {
+			fmt.Fprintln(stderr, "usage: ggservicectl status")
+			return request, false
+		}
+	case ggservice.CONTROL_COMMAND_RESTART:
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: ggservicectl restart <service name>")
+			return request, false
+		}
+		request.Service = args[1]
+	case ggservice.CONTROL_COMMAND_LOGS:
+		flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+		flags.SetOutput(stderr)
+		flags.BoolVar(&request.Follow, "follow", false, "keep printing new log lines")
+		flags.IntVar(&request.Lines, "n", 0, "amount of previous log lines to print (default 100)")
+		if flags.Parse(args[1:]) != nil || flags.NArg() != 0 {
+			return request, false
+		}
+	default:
+		fmt.Fprintln(stderr, "unknown command: "+request.Command)
+		return request, false
+	}
+	return request, true
+}
+
+// printResponse prints a response of the given command either as the raw JSON line or in a human-readable form.
+func printResponse(stdout io.Writer, output string, command string, line string, response ggservice.ControlResponse) {
+	if output == "json" {
+		fmt.Fprintln(stdout, line)
+		return
+	}
+	// status and restart respond with the services, there may be none
+	if command != ggservice.CONTROL_COMMAND_LOGS {
+		writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "NAME\tSTATE\tRUN SLEEP\tGRACEFUL SHUTDOWN\tLOG LEVEL")
+		for _, status := range response.Services {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\n", status.Name, status.State, status.RunSleepDuration, status.GracefulShutdownTime, status.LogLevel)
+		}
+		_ = writer.Flush()
+		return
+	}
+	fmt.Fprintln(stdout, response.Log)
+}
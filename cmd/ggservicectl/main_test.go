@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+// listenControl serves a control socket for the given services until the test ends, and returns the socket path.
+func listenControl(t *testing.T, services ...ggservice.IService) string {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "ggservice.sock")
+	control := ggservice.NewControl(socketPath)
+	control.Register(services...)
+	done := make(chan error, 1)
+	go func() {
+		done <- control.Listen()
+	}()
+	t.Cleanup(func() {
+		_ = control.Close()
+		err := <-done
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	for i := 0; i < 100; i++ {
+		if run([]string{"-socket", socketPath, "status"}, &bytes.Buffer{}, &bytes.Buffer{}) != EXIT_UNAVAILABLE {
+			return socketPath
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("control socket is not listening")
+	return ""
+}
+
+func TestRun(t *testing.T) {
+	service, err := ggservice.NewService("My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := ggservicetest.StartAsync(service, nil, func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, nil, nil)
+	defer func() {
+		_ = service.Stop()
+		<-stopped
+	}()
+	ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, 5*time.Second)
+
+	socketPath := listenControl(t, service)
+	emptySocketPath := listenControl(t)
+
+	tests := []struct {
+		name     string
+		args     []string
+		exitCode int
+		stdout   string
+		stderr   string
+	}{
+		{"status", []string{"-socket", socketPath, "status"}, EXIT_OK, "NAME        STATE    RUN SLEEP", ""},
+		{"status json", []string{"-socket", socketPath, "-o", "json", "status"}, EXIT_OK, `{"services":[{"name":"My Service","state":"running"`, ""},
+		{"status without services", []string{"-socket", emptySocketPath, "status"}, EXIT_OK, "NAME  STATE  RUN SLEEP  GRACEFUL SHUTDOWN  LOG LEVEL\n", ""},
+		{"restart unknown service", []string{"-socket", socketPath, "restart", "Unknown"}, EXIT_FAILED, "", "Unknown"},
+		{"restart unknown service json", []string{"-socket", socketPath, "-o", "json", "restart", "Unknown"}, EXIT_FAILED, `{"error":`, "Unknown"},
+		{"no command", []string{"-socket", socketPath}, EXIT_USAGE, "", "usage: ggservicectl"},
+		{"unknown command", []string{"-socket", socketPath, "start"}, EXIT_USAGE, "", "unknown command: start"},
+		{"unknown output format", []string{"-socket", socketPath, "-o", "yaml", "status"}, EXIT_USAGE, "", "unknown output format: yaml"},
+		{"restart without service", []string{"-socket", socketPath, "restart"}, EXIT_USAGE, "", "usage: ggservicectl restart"},
+		{"unreachable socket", []string{"-socket", filepath.Join(t.TempDir(), "missing.sock"), "status"}, EXIT_UNAVAILABLE, "", "missing.sock"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			exitCode := run(test.args, stdout, stderr)
+			if exitCode != test.exitCode {
+				t.Errorf("expected exit code %d, got %d (stderr: %s)", test.exitCode, exitCode, stderr)
+			}
+			if !strings.HasPrefix(stdout.String(), test.stdout) {
+				t.Errorf("expected output starting with %q, got %q", test.stdout, stdout)
+			}
+			if !strings.Contains(stderr.String(), test.stderr) {
+				t.Errorf("expected error output containing %q, got %q", test.stderr, stderr)
+			}
+		})
+	}
+
+	t.Run("status table", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+		run([]string{"-socket", socketPath, "status"}, stdout, &bytes.Buffer{})
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[1], "My Service") || !strings.Contains(lines[1], "running") {
+			t.Errorf("expected a header and a row for the service, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("status json lines", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+		run([]string{"-socket", socketPath, "-o", "json", "status"}, stdout, &bytes.Buffer{})
+		var response ggservice.ControlResponse
+		err := json.Unmarshal(stdout.Bytes(), &response)
+		if err != nil || len(response.Services) != 1 || response.Services[0].Name != "My Service" {
+			t.Errorf("expected one JSON response with the service, got %q (%v)", stdout, err)
+		}
+	})
+}
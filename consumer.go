@@ -0,0 +1,155 @@
+package ggservice
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DrainPolicy tells what a consumer does with the items left in its channel when the service stops.
+type DrainPolicy int
+
+// Drain policies
+const (
+	DRAIN_POLICY_ABANDON DrainPolicy = iota // 0: Items left in the channel are not handled, only the items being handled are finished
+	DRAIN_POLICY_DRAIN                      // 1: Items buffered in the channel are handled before the stop function runs
+)
+
+// Consumer configures the event-driven mode of a service, in which it handles items received from a channel instead of calling a run function in a loop. (see StartConsumer)
+type Consumer[T any] struct {
+	Items       <-chan T                                // Channel the items are received from, the service stops when it is closed
+	Handler     func(ctx context.Context, item T) error // Called for every item, an error is handled like an error returned by the run function
+	Concurrency int                                     // Amount of items handled at the same time (default: 1)
+	DrainPolicy DrainPolicy                             // What to do with the items left in the channel when the service stops (default: DRAIN_POLICY_ABANDON)
+}
+
+// StartConsumer starts the service in event-driven mode: it calls the handler of the consumer for every item received from its channel,
+// instead of calling a run function in a loop, so the run sleep duration is not used. Like Start, it blocks until the service stops.
+// The service stops when the channel is closed, or when the handler returns an error (ErrRestartService and Backoff steer the service like they do for the run function).
+// When the service stops, the items being handled are finished and the items left in the channel are handled or not according to the drain policy.
+// Items handled while draining get a context that is not cancelled.
+func StartConsumer[T any](service IService, consumer Consumer[T], startFunc func() error, stopFunc func() error, forceShutdownFunc func() error) error {
+	return service.StartScheduled(startFunc, consumer.runFunc(service), stopFunc, forceShutdownFunc)
+}
+
+// runFunc returns the run function of the consumer, which handles items until the service stops, the channel is closed or the handler fails.
+func (c Consumer[T]) runFunc(service IService) func(ctx context.Context) (time.Duration, error) {
+	progress, _ := service.(interface{ setRunProgress(isBusy bool) })
+	return func(ctx context.Context) (time.Duration, error) {
+		workerCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var busy atomic.Int32
+		var firstErr error
+		var once sync.Once
+		fail := func(err error) {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+		handle := func(ctx context.Context, item T) error {
+			busy.Add(1)
+			if progress != nil {
+				progress.setRunProgress(true)
+			}
+			err := c.handle(ctx, item)
+			if progress != nil {
+				progress.setRunProgress(busy.Add(-1) > 0) // handling an item is progress, a consumer waiting for items is not stuck
+			} else {
+				busy.Add(-1)
+			}
+			return err
+		}
+		if progress != nil {
+			progress.setRunProgress(false)
+		}
+
+		workers := sync.WaitGroup{}
+		for range max(c.Concurrency, 1) {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				for {
+					if workerCtx.Err() != nil {
+						// the service is stopping (not failing), so the drain policy applies
+						if ctx.Err() != nil && c.DrainPolicy == DRAIN_POLICY_DRAIN {
+							c.drain(context.WithoutCancel(ctx), handle, fail)
+						}
+						return
+					}
+
+					select {
+					case <-workerCtx.Done():
+						continue
+					case item, ok := <-c.Items:
+						if !ok {
+							fail(ErrStopService) // the channel is closed, so there is nothing left to do
+							return
+						}
+						if ctx.Err() != nil {
+							// select picks at random when the item and the stop are both ready, so an item received after the service
+							// started stopping is handled like the items left in the channel
+							if c.DrainPolicy == DRAIN_POLICY_DRAIN {
+								err := handle(context.WithoutCancel(ctx), item)
+								if err != nil {
+									fail(err)
+									return
+								}
+							}
+							continue
+						}
+						err := handle(workerCtx, item)
+						if err != nil {
+							fail(err)
+							return
+						}
+					}
+				}
+			}()
+		}
+		workers.Wait()
+		return 0, firstErr
+	}
+}
+
+// drain handles the items buffered in the channel, without waiting for new items.
+func (c Consumer[T]) drain(ctx context.Context, handle func(ctx context.Context, item T) error, fail func(err error)) {
+	for {
+		select {
+		case item, ok := <-c.Items:
+			if !ok {
+				return
+			}
+			err := handle(ctx, item)
+			if err != nil {
+				fail(err)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// handle calls the handler and turns a panic into an error.
+func (c Consumer[T]) handle(ctx context.Context, item T) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("handler panicked: %v", recovered)
+		}
+	}()
+	return c.Handler(ctx, item)
+}
+
+// setRunProgress marks the run function as busy from now on, or as idle, for the watchdog of the Notifier.
+func (s *Service) setRunProgress(isBusy bool) {
+	if !isBusy {
+		s.runFuncStartedAt.Store(0)
+		return
+	}
+	s.runFuncStartedAt.Store(time.Now().UnixNano())
+}
@@ -0,0 +1,107 @@
+package ggservice_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestStartConsumer(t *testing.T) {
+	t.Run("Closed channel", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		items := make(chan int)
+		go func() {
+			for i := 1; i <= 100; i++ {
+				items <- i
+			}
+			close(items)
+		}()
+
+		var sum atomic.Int64
+		err := ggservice.StartConsumer(service, ggservice.Consumer[int]{
+			Items: items,
+			Handler: func(ctx context.Context, item int) error {
+				sum.Add(int64(item))
+				return nil
+			},
+			Concurrency: 4,
+		}, nil, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if sum.Load() != 5050 {
+			t.Errorf("expected every item to be handled once, got sum %d", sum.Load())
+		}
+		if service.GetStopReason() != ggservice.STOP_REASON_REQUESTED {
+			t.Errorf("expected stop reason %s, got %s", ggservice.STOP_REASON_REQUESTED, service.GetStopReason())
+		}
+	})
+
+	for _, test := range []struct {
+		name        string
+		drainPolicy ggservice.DrainPolicy
+		handled     int32
+	}{
+		{"Abandon", ggservice.DRAIN_POLICY_ABANDON, 1},
+		{"Drain", ggservice.DRAIN_POLICY_DRAIN, 5},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			service := newService(t, "My Service")
+			service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+			items := make(chan int, 5)
+			for i := 0; i < 5; i++ {
+				items <- i
+			}
+			var handled atomic.Int32
+			var isCancelledWhileDraining atomic.Bool
+			err := ggservice.StartConsumer(service, ggservice.Consumer[int]{
+				Items: items,
+				Handler: func(ctx context.Context, item int) error {
+					if handled.Add(1) == 1 {
+						_ = service.Stop() // the service stops while the first item is handled
+					} else if ctx.Err() != nil {
+						isCancelledWhileDraining.Store(true)
+					}
+					return nil
+				},
+				DrainPolicy: test.drainPolicy,
+			}, nil, nil, nil)
+			if err != nil {
+				t.Error(err)
+			}
+			if handled.Load() != test.handled {
+				t.Errorf("expected %d handled items, got %d", test.handled, handled.Load())
+			}
+			if isCancelledWhileDraining.Load() {
+				t.Error("expected items handled while draining to get a context that is not cancelled")
+			}
+		})
+	}
+
+	t.Run("Handler error", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		handlerErr := errors.New("handler failed")
+		items := make(chan string, 1)
+		items <- "message"
+		err := ggservice.StartConsumer(service, ggservice.Consumer[string]{
+			Items: items,
+			Handler: func(ctx context.Context, item string) error {
+				return handlerErr
+			},
+		}, nil, nil, nil)
+		if !errors.Is(err, handlerErr) {
+			t.Errorf("expected handler error, got %v", err)
+		}
+		if service.GetStopReason() != ggservice.STOP_REASON_RUN_ERROR {
+			t.Errorf("expected stop reason %s, got %s", ggservice.STOP_REASON_RUN_ERROR, service.GetStopReason())
+		}
+	})
+}
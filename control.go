@@ -0,0 +1,389 @@
+package ggservice
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Control commands understood by the control listener (see ControlRequest)
+const (
+	CONTROL_COMMAND_STATUS  = "status"
+	CONTROL_COMMAND_RESTART = "restart"
+	CONTROL_COMMAND_LOGS    = "logs"
+)
+
+const (
+	controlLogBufferSize     = 1000            // amount of log lines kept for the logs command
+	controlDefaultLogLines   = 100             // amount of log lines returned when no amount is requested
+	controlRestartTimeMargin = 5 * time.Second // added to the graceful shutdown time when waiting for a restart
+	controlPollInterval      = 20 * time.Millisecond
+)
+
+// ControlRequest is a single request sent to the control listener as one line of JSON.
+type ControlRequest struct {
+	Command string `json:"command"`           // one of the CONTROL_COMMAND_* constants
+	Service string `json:"service,omitempty"` // service name, used by restart
+	Follow  bool   `json:"follow,omitempty"`  // keep streaming new log lines (logs)
+	Lines   int    `json:"lines,omitempty"`   // amount of previous log lines to send (logs)
+}
+
+// ControlResponse is written by the control listener as one line of JSON. The logs command writes one response per log line.
+type ControlResponse struct {
+	Error    string          `json:"error,omitempty"`
+	Services []ServiceStatus `json:"services,omitempty"`
+	Log      string          `json:"log,omitempty"`
+}
+
+// ServiceStatus describes a registered service in a ControlResponse.
+type ServiceStatus struct {
+	Name                 string `json:"name"`
+	State                string `json:"state"`
+	GracefulShutdownTime string `json:"gracefulShutdownTime"`
+	RunSleepDuration     string `json:"runSleepDuration"`
+	LogLevel             int    `json:"logLevel"`
+	StopReason           string `json:"stopReason,omitempty"` // Why the service stopped, empty if it has not stopped
+}
+
+// Control is a small control listener on a unix socket, used by ggservicectl to inspect and restart services and read their logs.
+type Control struct {
+	socketPath string
+	services   map[string]IService
+	names      []string // registration order, used for status output
+	listener   net.Listener
+	logs       *logBuffer
+	logOutput  io.Writer // log output before Listen was called, restored by Close
+	isClosed   bool
+	mutex      sync.Mutex
+}
+
+// NewControl creates a new control listener for the given unix socket path.
+func NewControl(socketPath string) *Control {
+	return &Control{
+		socketPath: socketPath,
+		services:   map[string]IService{},
+		logs:       newLogBuffer(controlLogBufferSize),
+	}
+}
+
+// Register makes the services available to the control listener by their name.
+func (c *Control) Register(services ...IService) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, service := range services {
+		if _, exists := c.services[service.GetName()]; !exists {
+			c.names = append(c.names, service.GetName())
+		}
+		c.services[service.GetName()] = service
+	}
+}
+
+// Listen listens on the unix socket and serves control requests until Close is called. (note: this is a blocking call)
+// Output of the standard logger is captured from here on, so it can be read with the logs command.
+func (c *Control) Listen() error {
+	// a socket file left behind by a previous process would make listening fail
+	if _, err := os.Stat(c.socketPath); err == nil {
+		conn, err := net.Dial("unix", c.socketPath)
+		if err == nil {
+			_ = conn.Close()
+			return errors.New("control socket is already in use: " + c.socketPath)
+		}
+		_ = os.Remove(c.socketPath)
+	}
+
+	listener, err := net.Listen("unix", c.socketPath)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	if c.isClosed {
+		c.mutex.Unlock()
+		_ = listener.Close()
+		return nil
+	}
+	c.listener = listener
+	c.logOutput = log.Writer()
+	log.SetOutput(io.MultiWriter(c.logOutput, c.logs))
+	c.mutex.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			c.mutex.Lock()
+			isClosed := c.isClosed
+			c.mutex.Unlock()
+			if isClosed {
+				return nil
+			}
+			return err
+		}
+		go c.serve(conn)
+	}
+}
+
+// Close stops the control listener, removes the socket file and restores the log output.
+func (c *Control) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.isClosed {
+		return nil
+	}
+	c.isClosed = true
+	c.logs.close()
+	if c.listener == nil {
+		return nil
+	}
+	log.SetOutput(c.logOutput)
+	return c.listener.Close() // the unix listener removes the socket file
+}
+
+// serve handles a single control connection.
+func (c *Control) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var request ControlRequest
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil || (errors.Is(err, io.EOF) && len(line) > 0) {
+		err = json.Unmarshal(line, &request)
+	}
+	encoder := json.NewEncoder(conn)
+	if err != nil {
+		_ = encoder.Encode(ControlResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	switch request.Command {
+	case CONTROL_COMMAND_STATUS:
+		_ = encoder.Encode(ControlResponse{Services: c.status()})
+	case CONTROL_COMMAND_RESTART:
+		err := c.restart(request.Service)
+		if err != nil {
+			_ = encoder.Encode(ControlResponse{Error: err.Error()})
+			return
+		}
+		_ = encoder.Encode(ControlResponse{Services: c.status(request.Service)})
+	case CONTROL_COMMAND_LOGS:
+		c.streamLogs(conn, encoder, request)
+	default:
+		_ = encoder.Encode(ControlResponse{Error: "unknown command: " + request.Command})
+	}
+}
+
+// status returns the status of the named services, or of all registered services if no names are given.
+func (c *Control) status(names ...string) []ServiceStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(names) == 0 {
+		names = c.names
+	}
+
+	statuses := make([]ServiceStatus, 0, len(names))
+	for _, name := range names {
+		service, exists := c.services[name]
+		if !exists {
+			continue
+		}
+		statuses = append(statuses, ServiceStatus{
+			Name:                 service.GetName(),
+			State:                service.GetState().String(),
+			GracefulShutdownTime: service.GetGracefulShutdownTime().String(),
+			RunSleepDuration:     service.GetRunSleepDuration().String(),
+			LogLevel:             service.GetLogLevel(),
+			StopReason:           stopReason(service),
+		})
+	}
+	return statuses
+}
+
+// restart restarts the named service and waits until it is running again.
+func (c *Control) restart(name string) error {
+	c.mutex.Lock()
+	service, exists := c.services[name]
+	c.mutex.Unlock()
+	if !exists {
+		return errors.New("unknown service: " + name)
+	}
+	if service.GetState() != STATE_RUNNING {
+		return errors.New("service is not running: " + name)
+	}
+
+	// services of this package count their restarts, which tells us when the restarted service has started
+	counter, hasCounter := service.(interface{ restartCount() uint64 })
+	var restarts uint64
+	if hasCounter {
+		restarts = counter.restartCount()
+	}
+
+	// Restart blocks for as long as the restarted service runs, so we only wait for it to be running again
+	done := make(chan error, 1)
+	go func() {
+		done <- service.Restart()
+	}()
+
+	ticker := time.NewTicker(controlPollInterval)
+	defer ticker.Stop()
+	timeout := time.After(service.GetGracefulShutdownTime() + controlRestartTimeMargin)
+	hasLeftRunning := false
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			if service.GetState() != STATE_RUNNING {
+				hasLeftRunning = true
+			} else if hasLeftRunning || (hasCounter && counter.restartCount() > restarts) {
+				return nil
+			}
+		case <-timeout:
+			if service.GetState() == STATE_RUNNING {
+				return nil
+			}
+			return errors.New("timed out waiting for restart of service: " + name)
+		}
+	}
+}
+
+// streamLogs writes the requested amount of previous log lines, and new lines as they arrive if follow is set.
+func (c *Control) streamLogs(conn net.Conn, encoder *json.Encoder, request ControlRequest) {
+	lines := request.Lines
+	if lines <= 0 {
+		lines = controlDefaultLogLines
+	}
+
+	previous, subscription := c.logs.subscribe(lines, request.Follow)
+	for _, line := range previous {
+		if encoder.Encode(ControlResponse{Log: line}) != nil {
+			return
+		}
+	}
+	if subscription == nil {
+		return
+	}
+	defer c.logs.unsubscribe(subscription)
+
+	// the client does not send anything after the request, so a read only returns once it disconnects
+	disconnected := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(disconnected)
+	}()
+
+	for {
+		select {
+		case line, ok := <-subscription:
+			if !ok || encoder.Encode(ControlResponse{Log: line}) != nil {
+				return
+			}
+		case <-disconnected:
+			return
+		}
+	}
+}
+
+// logBuffer keeps the latest log lines and passes new lines on to followers.
+type logBuffer struct {
+	lines       []string
+	size        int
+	next        int
+	isFull      bool
+	partial     string
+	subscribers map[chan string]struct{}
+	mutex       sync.Mutex
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{
+		lines:       make([]string, size),
+		size:        size,
+		subscribers: map[chan string]struct{}{},
+	}
+}
+
+// Write stores every complete line written to the buffer.
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	text := b.partial + string(p)
+	lines := strings.Split(text, "\n")
+	b.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		b.lines[b.next] = line
+		b.next = (b.next + 1) % b.size
+		if b.next == 0 {
+			b.isFull = true
+		}
+		for subscriber := range b.subscribers {
+			select {
+			case subscriber <- line:
+			default: // a slow follower misses lines rather than blocking the logger
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// subscribe returns the latest lines, and a channel receiving new lines if follow is set.
+func (b *logBuffer) subscribe(amount int, follow bool) ([]string, chan string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	count := b.next
+	if b.isFull {
+		count = b.size
+	}
+	if amount > count {
+		amount = count
+	}
+	previous := make([]string, 0, amount)
+	for i := amount; i > 0; i-- {
+		previous = append(previous, b.lines[(b.next-i+b.size)%b.size])
+	}
+
+	if !follow || b.subscribers == nil {
+		return previous, nil
+	}
+	subscription := make(chan string, controlLogBufferSize)
+	b.subscribers[subscription] = struct{}{}
+	return previous, subscription
+}
+
+func (b *logBuffer) unsubscribe(subscription chan string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if _, exists := b.subscribers[subscription]; exists {
+		delete(b.subscribers, subscription)
+		close(subscription)
+	}
+}
+
+// close ends all follow subscriptions.
+func (b *logBuffer) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for subscriber := range b.subscribers {
+		close(subscriber)
+	}
+	b.subscribers = nil
+}
+
+// stopReason returns the stop reason of the service for its status, or an empty string if it has not stopped or does not report why.
+func stopReason(service IService) string {
+	reasoned, ok := service.(interface{ GetStopReason() StopReason })
+	if !ok {
+		return ""
+	}
+	reason := reasoned.GetStopReason()
+	if reason == STOP_REASON_NONE {
+		return ""
+	}
+	return reason.String()
+}
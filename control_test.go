@@ -0,0 +1,163 @@
+package ggservice_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+// controlRequest sends a request to the control socket and returns all responses.
+func controlRequest(t *testing.T, socketPath string, request ggservice.ControlRequest) []ggservice.ControlResponse {
+	t.Helper()
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = json.NewEncoder(conn).Encode(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var responses []ggservice.ControlResponse
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var response ggservice.ControlResponse
+		err := json.Unmarshal(scanner.Bytes(), &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+func TestControl(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ggservice.sock")
+	service := newService(t, "My Service")
+	service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+	control := ggservice.NewControl(socketPath)
+	control.Register(service)
+
+	waitgroup := sync.WaitGroup{}
+	waitgroup.Add(2)
+	go func() {
+		defer waitgroup.Done()
+		err := control.Listen()
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer waitgroup.Done()
+		err := service.Start(nil, func() error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	for i := 0; i < 100 && service.GetState() != ggservice.STATE_RUNNING; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Run("status", func(t *testing.T) {
+		responses := controlRequest(t, socketPath, ggservice.ControlRequest{Command: ggservice.CONTROL_COMMAND_STATUS})
+		if len(responses) != 1 || len(responses[0].Services) != 1 {
+			t.Fatalf("unexpected responses: %+v", responses)
+		}
+		status := responses[0].Services[0]
+		if status.Name != "My Service" || status.State != "running" {
+			t.Errorf("unexpected status: %+v", status)
+		}
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		responses := controlRequest(t, socketPath, ggservice.ControlRequest{Command: ggservice.CONTROL_COMMAND_RESTART, Service: "My Service"})
+		if len(responses) != 1 || responses[0].Error != "" {
+			t.Fatalf("unexpected responses: %+v", responses)
+		}
+		if service.GetState() != ggservice.STATE_RUNNING {
+			t.Errorf("expected service to be running after restart, got %s", service.GetState())
+		}
+	})
+
+	t.Run("restart unknown service", func(t *testing.T) {
+		responses := controlRequest(t, socketPath, ggservice.ControlRequest{Command: ggservice.CONTROL_COMMAND_RESTART, Service: "Unknown"})
+		if len(responses) != 1 || responses[0].Error == "" {
+			t.Errorf("expected an error, got %+v", responses)
+		}
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		log.Println("hello from the log")
+		responses := controlRequest(t, socketPath, ggservice.ControlRequest{Command: ggservice.CONTROL_COMMAND_LOGS, Lines: 1})
+		if len(responses) != 1 || len(responses[0].Log) == 0 {
+			t.Fatalf("unexpected responses: %+v", responses)
+		}
+		if last := responses[0].Log; last[len(last)-len("hello from the log"):] != "hello from the log" {
+			t.Errorf("unexpected log line: %q", last)
+		}
+	})
+
+	err := service.Stop()
+	if err != nil {
+		t.Error(err)
+	}
+	err = control.Close()
+	if err != nil {
+		t.Error(err)
+	}
+	waitgroup.Wait()
+}
+
+func TestControl_stopReason(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ggservice.sock")
+	stopped := ggservicetest.NewFakeService("Stopped Service")
+	stopped.Return("GetStopReason", ggservice.STOP_REASON_STOP)
+	decorated := struct{ ggservice.IService }{ggservicetest.NewFakeService("Decorated Service")} // does not report why it stopped
+
+	control := ggservice.NewControl(socketPath)
+	control.Register(stopped, decorated)
+	done := make(chan error, 1)
+	go func() {
+		done <- control.Listen()
+	}()
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("unix", socketPath)
+		if err == nil {
+			_ = conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	responses := controlRequest(t, socketPath, ggservice.ControlRequest{Command: ggservice.CONTROL_COMMAND_STATUS})
+	if len(responses) != 1 || len(responses[0].Services) != 2 {
+		t.Fatalf("unexpected responses: %+v", responses)
+	}
+	if responses[0].Services[0].StopReason != "stop" || responses[0].Services[1].StopReason != "" {
+		t.Errorf("expected the stop reason only for the service reporting it, got %+v", responses[0].Services)
+	}
+
+	err := control.Close()
+	if err != nil {
+		t.Error(err)
+	}
+	err = <-done
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,34 @@
+package ggservice
+
+import (
+	"errors"
+	"time"
+)
+
+// Errors the run function can return to steer the run loop of Start. They are also recognized when wrapped.
+var (
+	ErrStopService    = errors.New("stop service")    // Stops the service gracefully, the stop function still runs and Start returns nil
+	ErrRestartService = errors.New("restart service") // Restarts the service like Restart does: stop function, then start function again
+	ErrSkipSleep      = errors.New("skip sleep")      // Runs the run function again right away, without sleeping the run sleep duration
+)
+
+// Errors returned by Start, Stop and Reload when they are called in the wrong state. They are wrapped with the name of the service.
+var (
+	ErrAlreadyStarted = errors.New("service already started") // Start was called while the service is started
+	ErrNotRunning     = errors.New("service is not running")  // Stop was called while the service is not running
+	ErrNotSupported   = errors.New("not supported")           // Reload was called on a service whose Runnable does not implement Reloader, or a setting is not supported by the service
+)
+
+// BackoffError makes the run loop sleep for Duration instead of the run sleep duration before the next run. (see Backoff)
+type BackoffError struct {
+	Duration time.Duration
+}
+
+func (e *BackoffError) Error() string {
+	return "back off for " + e.Duration.String()
+}
+
+// Backoff returns an error the run function can return to sleep for the given duration before it runs again.
+func Backoff(duration time.Duration) error {
+	return &BackoffError{Duration: duration}
+}
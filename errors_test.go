@@ -0,0 +1,91 @@
+package ggservice_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestService_RunLoopErrors(t *testing.T) {
+	t.Run("ErrStopService", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		runs, stops := 0, 0
+		err := service.Start(nil, func() error {
+			runs++
+			if runs == 3 {
+				return fmt.Errorf("done: %w", ggservice.ErrStopService)
+			}
+			return nil
+		}, func() error {
+			stops++
+			return nil
+		}, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if runs != 3 || stops != 1 {
+			t.Errorf("expected 3 runs and 1 stop, got %d runs and %d stops", runs, stops)
+		}
+		if service.GetState() != ggservice.STATE_STOPPED {
+			t.Errorf("expected service to be stopped, got %s", service.GetState())
+		}
+	})
+
+	t.Run("ErrRestartService", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		starts, runs, stops := 0, 0, 0
+		err := service.Start(func() error {
+			starts++
+			return nil
+		}, func() error {
+			runs++
+			if runs == 1 {
+				return ggservice.ErrRestartService
+			}
+			return ggservice.ErrStopService
+		}, func() error {
+			stops++
+			return nil
+		}, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if starts != 2 || runs != 2 || stops != 2 {
+			t.Errorf("expected 2 starts, runs and stops, got %d starts, %d runs and %d stops", starts, runs, stops)
+		}
+	})
+
+	t.Run("ErrSkipSleep and Backoff", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		service.SetRunSleepDuration(time.Hour)
+
+		runs := 0
+		startTime := time.Now()
+		err := service.Start(nil, func() error {
+			runs++
+			switch runs {
+			case 1:
+				return ggservice.ErrSkipSleep
+			case 2:
+				return ggservice.Backoff(5 * time.Millisecond)
+			}
+			return ggservice.ErrStopService
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if runs != 3 {
+			t.Errorf("expected 3 runs, got %d", runs)
+		}
+		if elapsed := time.Since(startTime); elapsed < 5*time.Millisecond || elapsed > time.Minute {
+			t.Errorf("unexpected run time: %v", elapsed)
+		}
+	})
+}
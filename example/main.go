@@ -16,18 +16,23 @@ var ServiceName1 = "My Service 1"
 var ServiceName2 = "My Service 2"
 var ServiceName3 = "My Service 3"
 var ServiceName4 = "My Service 4"
+var ServiceName5 = "My Service 5"
 
 // loadService - creates a new service and starts it
 func loadService() {
 	// starting the service (please note you can choose to not implement any of these by using nil instead)
 	waitgroup := &sync.WaitGroup{}
-	waitgroup.Add(4)
+	waitgroup.Add(5)
 	go func() {
 		// creating new service with name and graceful shutdown time duration
-		service := ggservice.NewService(ServiceName1)
-		service.SetGracefulShutdownTime(5 * time.Second)
-		service.SetLogLevel(ggservice.LOG_LEVEL_INFO)
-		err := service.Start(start, run, stop, forceShutdown) // this is a blocking call
+		service, err := ggservice.NewService(ServiceName1,
+			ggservice.WithGracefulShutdownTime(5*time.Second),
+			ggservice.WithLogLevel(ggservice.LOG_LEVEL_INFO),
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = service.Start(start, run, stop, forceShutdown) // this is a blocking call
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -36,10 +41,14 @@ func loadService() {
 
 	go func() {
 		// creating new service with name and graceful shutdown time duration
-		service := ggservice.NewService(ServiceName2)
-		service.SetGracefulShutdownTime(5 * time.Second)
-		service.SetLogLevel(ggservice.LOG_LEVEL_INFO)
-		err := service.Start(nil, run2, nil, nil)
+		service, err := ggservice.NewService(ServiceName2,
+			ggservice.WithGracefulShutdownTime(5*time.Second),
+			ggservice.WithLogLevel(ggservice.LOG_LEVEL_INFO),
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = service.Start(nil, run2, nil, nil)
 		if err != nil {
 			log.Println(err)
 		}
@@ -48,11 +57,15 @@ func loadService() {
 
 	go func() {
 		// creating new service with name and graceful shutdown time duration
-		service := ggservice.NewService(ServiceName3)
-		service.SetGracefulShutdownTime(5 * time.Second)
-		service.SetRunSleepDuration(12 * time.Second)
-		service.SetLogLevel(ggservice.LOG_LEVEL_INFO)
-		err := service.Start(nil, run3, nil, nil)
+		service, err := ggservice.NewService(ServiceName3,
+			ggservice.WithGracefulShutdownTime(5*time.Second),
+			ggservice.WithRunSleepDuration(12*time.Second),
+			ggservice.WithLogLevel(ggservice.LOG_LEVEL_INFO),
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = service.Start(nil, run3, nil, nil)
 		if err != nil {
 			log.Println(err)
 		}
@@ -61,11 +74,32 @@ func loadService() {
 
 	go func() {
 		// creating new service with name and graceful shutdown time duration
-		service := ggservice.NewService(ServiceName4)
-		service.SetGracefulShutdownTime(5 * time.Second)
-		service.SetRunSleepDuration(12 * time.Second)
-		service.SetLogLevel(ggservice.LOG_LEVEL_INFO)
-		err := service.Start(nil, nil, nil, nil)
+		service, err := ggservice.NewService(ServiceName4,
+			ggservice.WithGracefulShutdownTime(5*time.Second),
+			ggservice.WithRunSleepDuration(12*time.Second),
+			ggservice.WithLogLevel(ggservice.LOG_LEVEL_INFO),
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = service.Start(nil, nil, nil, nil)
+		if err != nil {
+			log.Println(err)
+		}
+		waitgroup.Done()
+	}()
+
+	go func() {
+		// creating new typed service, its start function creates the state passed to its run and stop functions
+		service, err := ggservice.NewTypedService[*workState](ServiceName5,
+			ggservice.WithGracefulShutdownTime(5*time.Second),
+			ggservice.WithRunSleepDuration(3*time.Second),
+			ggservice.WithLogLevel(ggservice.LOG_LEVEL_INFO),
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = service.StartTyped(start5, run5, stop5, nil)
 		if err != nil {
 			log.Println(err)
 		}
@@ -118,3 +152,27 @@ func run3() error {
 	log.Println("work3 (with custom loop time.Sleep)")
 	return nil
 }
+
+// workState is the state shared between the start, run and stop functions of service 5
+type workState struct {
+	startedAt time.Time
+	runs      int
+}
+
+// start5 creates the state of service 5, it is created again when the service restarts
+func start5() (*workState, error) {
+	return &workState{startedAt: time.Now()}, nil
+}
+
+// run5 loops with the state created by start5
+func run5(state *workState) error {
+	state.runs++
+	log.Printf("work5 (run %d)\n", state.runs)
+	return nil
+}
+
+// stop5 receives the state created by start5
+func stop5(state *workState) error {
+	log.Printf("work5 ran %d times in %v\n", state.runs, time.Since(state.startedAt))
+	return nil
+}
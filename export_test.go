@@ -0,0 +1,10 @@
+package ggservice
+
+// SetExit replaces the function that ends the program, and returns a function that restores it.
+func SetExit(f func(code int)) (restore func()) {
+	previous := exit
+	exit = f
+	return func() {
+		exit = previous
+	}
+}
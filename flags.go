@@ -0,0 +1,201 @@
+package ggservice
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logLevelNames are the names of the log levels, used by flags and environment variables.
+var logLevelNames = []string{
+	LOG_LEVEL_NONE:  "none",
+	LOG_LEVEL_ERROR: "error",
+	LOG_LEVEL_WARN:  "warn",
+	LOG_LEVEL_INFO:  "info",
+	LOG_LEVEL_ALL:   "all",
+}
+
+// setting is a setting of a service that can be set by a flag (see BindFlags) or an environment variable (see LoadEnv).
+type setting struct {
+	flagName    string
+	envName     string
+	usage       string
+	isSupported func(service IService) bool // nil if every IService has the setting
+	get         func(service IService) string
+	set         func(service IService, value string) error
+}
+
+// shutdownHookTimeoutSetting is implemented by services with a shutdown hook timeout, like *Service.
+type shutdownHookTimeoutSetting interface {
+	GetShutdownHookTimeout() time.Duration
+	SetShutdownHookTimeout(shutdownHookTimeout time.Duration) error
+}
+
+// settings are the settings bound by BindFlags and LoadEnv.
+var settings = []setting{
+	{
+		flagName: "graceful-timeout",
+		envName:  "GRACEFUL_SHUTDOWN_TIME",
+		usage:    "for how long the service may shut down before it is forced to",
+		get: func(service IService) string {
+			return service.GetGracefulShutdownTime().String()
+		},
+		set: func(service IService, value string) error {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			return service.SetGracefulShutdownTime(duration)
+		},
+	},
+	{
+		flagName: "run-interval",
+		envName:  "RUN_SLEEP_DURATION",
+		usage:    "for how long the run loop sleeps between runs",
+		get: func(service IService) string {
+			return service.GetRunSleepDuration().String()
+		},
+		set: func(service IService, value string) error {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			return service.SetRunSleepDuration(duration)
+		},
+	},
+	{
+		flagName: "log-level",
+		envName:  "LOG_LEVEL",
+		usage:    "which messages the service logs: " + strings.Join(logLevelNames, ", "),
+		get: func(service IService) string {
+			return logLevelName(service.GetLogLevel())
+		},
+		set: func(service IService, value string) error {
+			logLevel, err := parseLogLevel(value)
+			if err != nil {
+				return err
+			}
+			return service.SetLogLevel(logLevel)
+		},
+	},
+	{
+		flagName: "shutdown-hook-timeout",
+		envName:  "SHUTDOWN_HOOK_TIMEOUT",
+		usage:    "for how long every closer and shutdown function may run, 0 for the graceful shutdown time",
+		isSupported: func(service IService) bool {
+			_, ok := service.(shutdownHookTimeoutSetting)
+			return ok
+		},
+		get: func(service IService) string {
+			return service.(shutdownHookTimeoutSetting).GetShutdownHookTimeout().String()
+		},
+		set: func(service IService, value string) error {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			return service.(shutdownHookTimeoutSetting).SetShutdownHookTimeout(duration)
+		},
+	},
+	{
+		flagName: "pid-file",
+		envName:  "PID_FILE",
+		usage:    "the file the process id is written to, so only one instance can run",
+		get: func(service IService) string {
+			return service.GetPIDFile()
+		},
+		set: func(service IService, value string) error {
+			return service.SetPIDFile(value)
+		},
+	},
+}
+
+// BindFlags defines the flags -graceful-timeout, -run-interval, -log-level, -shutdown-hook-timeout and -pid-file on the flag set (flag.CommandLine if nil).
+// The flags set the settings of the service when the flag set is parsed, and are validated like the setters. Their defaults are the current settings.
+// Flags of settings the service does not have (like -shutdown-hook-timeout for an IService other than *Service) are not defined.
+func BindFlags(flags *flag.FlagSet, service IService) {
+	if flags == nil {
+		flags = flag.CommandLine
+	}
+	for i := range settings {
+		if !settings[i].supports(service) {
+			continue
+		}
+		flags.Var(&settingValue{service: service, setting: &settings[i]}, settings[i].flagName, settings[i].usage)
+	}
+}
+
+// LoadEnv sets the settings of the service from the environment variables with the given prefix,
+// for example MYAPP_GRACEFUL_SHUTDOWN_TIME=10s, MYAPP_RUN_SLEEP_DURATION=1s, MYAPP_LOG_LEVEL=warn, MYAPP_SHUTDOWN_HOOK_TIMEOUT=5s and MYAPP_PID_FILE=/run/app.pid.
+// Variables that are not set leave their settings as they are. It returns the errors of all invalid variables,
+// and ErrNotSupported for variables of settings the service does not have.
+func LoadEnv(prefix string, service IService) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	var errs []error
+	for _, setting := range settings {
+		key := prefix + setting.envName
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if !setting.supports(service) {
+			errs = append(errs, fmt.Errorf("%s: %w", key, ErrNotSupported))
+			continue
+		}
+		err := setting.set(service, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// supports reports whether the service has the setting.
+func (s *setting) supports(service IService) bool {
+	return s.isSupported == nil || s.isSupported(service)
+}
+
+// settingValue is the flag.Value of a setting of a service.
+type settingValue struct {
+	service IService
+	setting *setting
+}
+
+func (v *settingValue) String() string {
+	if v.service == nil { // the zero value, created by the flag package to print the defaults
+		return ""
+	}
+	return v.setting.get(v.service)
+}
+
+func (v *settingValue) Set(value string) error {
+	return v.setting.set(v.service, value)
+}
+
+// parseLogLevel parses the name (like "warn") or the number of a log level.
+func parseLogLevel(value string) (int, error) {
+	for logLevel, name := range logLevelNames {
+		if strings.EqualFold(value, name) {
+			return logLevel, nil
+		}
+	}
+	logLevel, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("unknown log level: %q", value)
+	}
+	return logLevel, nil
+}
+
+// logLevelName returns the name of the log level, or its number if it is unknown.
+func logLevelName(logLevel int) string {
+	if logLevel >= 0 && logLevel < len(logLevelNames) {
+		return logLevelNames[logLevel]
+	}
+	return strconv.Itoa(logLevel)
+}
@@ -0,0 +1,111 @@
+package ggservice_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestBindFlags(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		service := newService(t, "My Service")
+		flags := flag.NewFlagSet("my-service", flag.ContinueOnError)
+		ggservice.BindFlags(flags, service)
+
+		err := flags.Parse([]string{"-graceful-timeout", "10s", "-run-interval=1s", "-log-level", "warn", "-shutdown-hook-timeout", "3s", "-pid-file", "my-service.pid"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if service.GetGracefulShutdownTime() != 10*time.Second || service.GetRunSleepDuration() != time.Second || service.GetLogLevel() != ggservice.LOG_LEVEL_WARN ||
+			service.GetShutdownHookTimeout() != 3*time.Second || service.GetPIDFile() != "my-service.pid" {
+			t.Error("expected the flags to set the settings of the service")
+		}
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"-graceful-timeout", "-1s"},
+			{"-run-interval", "soon"},
+			{"-log-level", "verbose"},
+			{"-log-level", "7"},
+		} {
+			service := newService(t, "My Service")
+			flags := flag.NewFlagSet("my-service", flag.ContinueOnError)
+			flags.SetOutput(&bytes.Buffer{})
+			ggservice.BindFlags(flags, service)
+			if flags.Parse(args) == nil {
+				t.Errorf("%v: expected error", args)
+			}
+		}
+	})
+
+	t.Run("Unsupported settings", func(t *testing.T) {
+		service := struct{ ggservice.IService }{newService(t, "My Service")} // without a shutdown hook timeout
+		flags := flag.NewFlagSet("my-service", flag.ContinueOnError)
+		ggservice.BindFlags(flags, service)
+		if flags.Lookup("shutdown-hook-timeout") != nil || flags.Lookup("graceful-timeout") == nil {
+			t.Error("expected only the flags of the settings of the service")
+		}
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_INFO))
+		flags := flag.NewFlagSet("my-service", flag.ContinueOnError)
+		ggservice.BindFlags(flags, service)
+
+		usage := &bytes.Buffer{}
+		flags.SetOutput(usage)
+		flags.PrintDefaults()
+		for _, expected := range []string{"-graceful-timeout", "(default 5s)", "-log-level", "(default info)", "0 for the graceful shutdown time", "-pid-file"} {
+			if !strings.Contains(usage.String(), expected) {
+				t.Errorf("expected usage to contain %q, got:\n%s", expected, usage)
+			}
+		}
+	})
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Run("Set", func(t *testing.T) {
+		t.Setenv("MYAPP_GRACEFUL_SHUTDOWN_TIME", "10s")
+		t.Setenv("MYAPP_LOG_LEVEL", "WARN")
+		t.Setenv("MYAPP_RUN_SLEEP_DURATION", "1s")
+
+		service := newService(t, "My Service", ggservice.WithShutdownHookTimeout(3*time.Second))
+		err := ggservice.LoadEnv("MYAPP", service)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if service.GetGracefulShutdownTime() != 10*time.Second || service.GetLogLevel() != ggservice.LOG_LEVEL_WARN || service.GetRunSleepDuration() != time.Second {
+			t.Error("expected the environment variables to set the settings of the service")
+		}
+		if service.GetShutdownHookTimeout() != 3*time.Second {
+			t.Error("expected settings without environment variables to be left as they are")
+		}
+	})
+
+	t.Run("Unsupported settings", func(t *testing.T) {
+		t.Setenv("MYAPP_SHUTDOWN_HOOK_TIMEOUT", "3s")
+
+		service := struct{ ggservice.IService }{newService(t, "My Service")} // without a shutdown hook timeout
+		err := ggservice.LoadEnv("MYAPP", service)
+		if !errors.Is(err, ggservice.ErrNotSupported) || !strings.Contains(err.Error(), "MYAPP_SHUTDOWN_HOOK_TIMEOUT") {
+			t.Errorf("expected the variable to be not supported, got %v", err)
+		}
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		t.Setenv("MYAPP_GRACEFUL_SHUTDOWN_TIME", "-1s")
+		t.Setenv("MYAPP_LOG_LEVEL", "verbose")
+
+		service := newService(t, "My Service")
+		err := ggservice.LoadEnv("MYAPP_", service)
+		if err == nil || !strings.Contains(err.Error(), "MYAPP_GRACEFUL_SHUTDOWN_TIME") || !strings.Contains(err.Error(), "MYAPP_LOG_LEVEL") {
+			t.Errorf("expected errors for both variables, got %v", err)
+		}
+	})
+}
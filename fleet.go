@@ -0,0 +1,508 @@
+package ggservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fleetDefaultRestartDelay is the delay before a service of a fleet is restarted by its restart policy, if the config does not set one.
+const fleetDefaultRestartDelay = time.Second
+
+// Factory creates the Runnable of a service from the implementation-specific config of the service (nil if the config has none).
+// The service is created and configured by the fleet, so the factory can use it (for example to register closers with RegisterCloser).
+type Factory func(service *Service, config json.RawMessage) (Runnable, error)
+
+// Registry maps the names of service implementations to the factories creating them, so a fleet config can refer to them by name. (see LoadFleet)
+type Registry struct {
+	factories map[string]Factory
+	mutex     sync.Mutex
+}
+
+// NewRegistry creates a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: map[string]Factory{}}
+}
+
+// Register registers the factory of the implementation with the given name, it returns an error if the name is empty or already registered.
+func (r *Registry) Register(implementation string, factory Factory) error {
+	if implementation == "" {
+		return errors.New("the name of an implementation can not be empty")
+	}
+	if factory == nil {
+		return fmt.Errorf("the factory of implementation %q can not be nil", implementation)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.factories[implementation]; ok {
+		return fmt.Errorf("implementation %q is already registered", implementation)
+	}
+	r.factories[implementation] = factory
+	return nil
+}
+
+func (r *Registry) factory(implementation string) (Factory, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	factory, ok := r.factories[implementation]
+	return factory, ok
+}
+
+// RestartPolicy tells whether a fleet starts a service again when it stopped by itself.
+type RestartPolicy int
+
+// Restart policies
+const (
+	RESTART_POLICY_NEVER      RestartPolicy = iota // 0: The service is not started again ("never")
+	RESTART_POLICY_ON_FAILURE                      // 1: The service is started again when it stopped because of an error ("on-failure")
+	RESTART_POLICY_ALWAYS                          // 2: The service is started again whenever it stopped by itself ("always")
+)
+
+// String returns the name of the restart policy, as used in a fleet config.
+func (policy RestartPolicy) String() string {
+	switch policy {
+	case RESTART_POLICY_NEVER:
+		return "never"
+	case RESTART_POLICY_ON_FAILURE:
+		return "on-failure"
+	case RESTART_POLICY_ALWAYS:
+		return "always"
+	}
+	return "unknown"
+}
+
+func (policy RestartPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(policy.String())
+}
+
+func (policy *RestartPolicy) UnmarshalJSON(data []byte) error {
+	var name string
+	err := json.Unmarshal(data, &name)
+	if err != nil {
+		return fmt.Errorf("restart policy must be a string: %w", err)
+	}
+	for _, known := range []RestartPolicy{RESTART_POLICY_NEVER, RESTART_POLICY_ON_FAILURE, RESTART_POLICY_ALWAYS} {
+		if name == known.String() {
+			*policy = known
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown restart policy %q (expected never, on-failure or always)", name)
+}
+
+// shouldRestart reports whether a service that stopped for the reason with the error is started again.
+// A service that was stopped (by Stop, a signal, an upgrade or a forced shutdown) is never started again.
+func (policy RestartPolicy) shouldRestart(reason StopReason, err error) bool {
+	switch reason {
+	case STOP_REASON_STOP, STOP_REASON_SIGNAL, STOP_REASON_UPGRADE, STOP_REASON_FORCE_TIMEOUT, STOP_REASON_FORCE_SHUTDOWN:
+		return false
+	}
+	switch policy {
+	case RESTART_POLICY_ON_FAILURE:
+		return err != nil || reason == STOP_REASON_START_ERROR || reason == STOP_REASON_RUN_ERROR || reason == STOP_REASON_GO_ERROR
+	case RESTART_POLICY_ALWAYS:
+		return true
+	}
+	return false
+}
+
+// FleetConfig declares the services of a fleet, it is read from a JSON file by LoadFleet.
+type FleetConfig struct {
+	Services []ServiceConfig `json:"services"`
+}
+
+// ServiceConfig declares a service of a fleet. The settings are validated like the setters of the service,
+// durations are written like "10s" and the log level by name (like "warn"). Settings that are left out keep their defaults.
+type ServiceConfig struct {
+	Name                 string          `json:"name"`                             // Name of the service, unique within the fleet
+	Implementation       string          `json:"implementation"`                   // Name the factory of the service is registered with
+	Enabled              *bool           `json:"enabled,omitempty"`                // Whether the service runs (default: true)
+	GracefulShutdownTime string          `json:"graceful_shutdown_time,omitempty"` // (see SetGracefulShutdownTime)
+	RunSleepDuration     string          `json:"run_sleep_duration,omitempty"`     // (see SetRunSleepDuration)
+	LogLevel             string          `json:"log_level,omitempty"`              // (see SetLogLevel)
+	ShutdownHookTimeout  string          `json:"shutdown_hook_timeout,omitempty"`  // (see SetShutdownHookTimeout)
+	PIDFile              string          `json:"pid_file,omitempty"`               // (see SetPIDFile)
+	Restart              RestartPolicy   `json:"restart,omitempty"`                // Whether the service is started again when it stopped by itself (default: "never")
+	RestartDelay         string          `json:"restart_delay,omitempty"`          // Delay before the service is started again (default: "1s")
+	DependsOn            []string        `json:"depends_on,omitempty"`             // Services that must be running before the service starts
+	Config               json.RawMessage `json:"config,omitempty"`                 // Implementation-specific config, passed to the factory
+}
+
+// isEnabled reports whether the service runs, services are enabled unless disabled in the config.
+func (c ServiceConfig) isEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// settingValues returns the settings of the config by the environment variable names of the settings (see LoadEnv).
+func (c ServiceConfig) settingValues() map[string]string {
+	return map[string]string{
+		"GRACEFUL_SHUTDOWN_TIME": c.GracefulShutdownTime,
+		"RUN_SLEEP_DURATION":     c.RunSleepDuration,
+		"LOG_LEVEL":              c.LogLevel,
+		"SHUTDOWN_HOOK_TIMEOUT":  c.ShutdownHookTimeout,
+		"PID_FILE":               c.PIDFile,
+	}
+}
+
+// ParseFleetConfig parses a JSON fleet config. Unknown fields are rejected, and syntax errors and values of the wrong type tell the line they were found on.
+func ParseFleetConfig(data []byte) (*FleetConfig, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	config := &FleetConfig{}
+	err := decoder.Decode(config)
+	if err != nil {
+		// only syntax and type errors tell where they were found, unknown fields are found once the whole config was read
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("line %d: %w", 1+bytes.Count(data[:syntaxErr.Offset], []byte("\n")), err)
+		}
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("line %d: %w", 1+bytes.Count(data[:typeErr.Offset], []byte("\n")), err)
+		}
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("line %d: unexpected data after the fleet config", 1+bytes.Count(data[:decoder.InputOffset()], []byte("\n")))
+	}
+	return config, nil
+}
+
+// Fleet is a set of services declared by a fleet config, started in the order of their dependencies. (see LoadFleet)
+type Fleet struct {
+	services []*fleetMember // in start order, dependencies before the services depending on them
+	stopped  chan struct{}
+	once     sync.Once
+	started  bool
+	mutex    sync.Mutex
+}
+
+// fleetMember is a service of a fleet with its config.
+type fleetMember struct {
+	config       ServiceConfig
+	service      *Service
+	factory      Factory
+	runnable     Runnable
+	restartDelay time.Duration
+	dependencies []*fleetMember
+	running      chan struct{} // closed when the service is running for the first time
+	done         chan struct{} // closed when the fleet does not start the service again
+	runningOnce  sync.Once
+	err          error
+}
+
+// LoadFleet reads the JSON fleet config file at the given path, and creates its enabled services with the factories of the registry. (see NewFleet)
+func LoadFleet(path string, registry *Registry) (*Fleet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseFleetConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	fleet, err := NewFleet(config, registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fleet, nil
+}
+
+// NewFleet validates the fleet config, and creates its enabled services with the factories of the registry.
+// It returns all errors of the config at once: missing and duplicate names, unknown implementations, invalid settings,
+// dependencies on unknown or disabled services and dependency cycles. The factories are only called once the whole config is valid,
+// in start order. If a factory fails, the errors of all factories are returned, and the closers registered on the services are closed.
+func NewFleet(config *FleetConfig, registry *Registry) (*Fleet, error) {
+	var errs []error
+	configs := map[string]ServiceConfig{}
+	for i, serviceConfig := range config.Services {
+		if serviceConfig.Name == "" {
+			errs = append(errs, fmt.Errorf("services[%d]: the name of a service can not be empty", i))
+			continue
+		}
+		if _, ok := configs[serviceConfig.Name]; ok {
+			errs = append(errs, fmt.Errorf("service %q: the name is used by another service", serviceConfig.Name))
+			continue
+		}
+		configs[serviceConfig.Name] = serviceConfig
+	}
+
+	fleet := &Fleet{stopped: make(chan struct{})}
+	services := map[string]*fleetMember{}
+	for _, serviceConfig := range config.Services {
+		if configs[serviceConfig.Name].Name == "" || !serviceConfig.isEnabled() {
+			continue
+		}
+		member, memberErrs := newFleetMember(serviceConfig, configs, registry)
+		for _, err := range memberErrs {
+			errs = append(errs, fmt.Errorf("service %q: %w", serviceConfig.Name, err))
+		}
+		if member == nil {
+			continue
+		}
+		services[serviceConfig.Name] = member
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// dependencies start first, so the services are ordered depth first
+	visiting := map[string]bool{}
+	visited := map[string]bool{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if visited[name] {
+			return nil
+		}
+		path = append(path, name)
+		if visiting[name] {
+			return fmt.Errorf("dependency cycle: %s", strings.Join(path, " -> "))
+		}
+		visiting[name] = true
+		member := services[name]
+		for _, dependency := range member.config.DependsOn {
+			err := visit(dependency, path)
+			if err != nil {
+				return err
+			}
+			member.dependencies = append(member.dependencies, services[dependency])
+		}
+		visiting[name] = false
+		visited[name] = true
+		fleet.services = append(fleet.services, member)
+		return nil
+	}
+	for _, serviceConfig := range config.Services {
+		if _, ok := services[serviceConfig.Name]; ok {
+			err := visit(serviceConfig.Name, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, member := range fleet.services {
+		runnable, err := member.factory(member.service, member.config.Config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("service %q: implementation %q: %w", member.config.Name, member.config.Implementation, err))
+			continue
+		}
+		member.runnable = runnable
+	}
+	if len(errs) > 0 {
+		// the fleet is not created, so release what the factories registered, in reverse start order
+		for i := len(fleet.services) - 1; i >= 0; i-- {
+			member := fleet.services[i]
+			err := member.service.runShutdownHooks()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("service %q: %w", member.config.Name, err))
+			}
+		}
+		return nil, errors.Join(errs...)
+	}
+	return fleet, nil
+}
+
+// newFleetMember creates the service of the config, configs are the configs of all services of the fleet by name.
+// It returns all errors of the config. The factory is not called yet, so it does not open anything for an invalid config.
+func newFleetMember(config ServiceConfig, configs map[string]ServiceConfig, registry *Registry) (*fleetMember, []error) {
+	var errs []error
+	factory, ok := registry.factory(config.Implementation)
+	if !ok {
+		errs = append(errs, fmt.Errorf("unknown implementation %q", config.Implementation))
+	}
+	for _, dependency := range config.DependsOn {
+		dependencyConfig, ok := configs[dependency]
+		if !ok {
+			errs = append(errs, fmt.Errorf("depends on unknown service %q", dependency))
+		} else if !dependencyConfig.isEnabled() {
+			errs = append(errs, fmt.Errorf("depends on disabled service %q", dependency))
+		}
+	}
+	restartDelay := fleetDefaultRestartDelay
+	if config.RestartDelay != "" {
+		var err error
+		restartDelay, err = time.ParseDuration(config.RestartDelay)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("restart_delay: %w", err))
+		} else if restartDelay < 0 {
+			errs = append(errs, fmt.Errorf("restart_delay: negative restart delay: %v", restartDelay))
+		}
+	}
+
+	service, err := NewService(config.Name)
+	if err != nil {
+		return nil, append(errs, err)
+	}
+	values := config.settingValues()
+	for _, setting := range settings {
+		value := values[setting.envName]
+		if value == "" {
+			continue
+		}
+		err := setting.set(service, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", strings.ToLower(setting.envName), err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	member := &fleetMember{
+		config:       config,
+		service:      service,
+		factory:      factory,
+		restartDelay: restartDelay,
+		running:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	service.Hooks().OnRunning(func(Event) {
+		member.runningOnce.Do(func() {
+			close(member.running)
+		})
+	})
+	return member, nil
+}
+
+// Services returns the services of the fleet in start order, dependencies before the services depending on them.
+func (f *Fleet) Services() []IService {
+	services := make([]IService, len(f.services))
+	for i, member := range f.services {
+		services[i] = member.service
+	}
+	return services
+}
+
+// Service returns the service of the fleet with the given name, or nil if the fleet has no (enabled) service with the name.
+func (f *Fleet) Service(name string) IService {
+	for _, member := range f.services {
+		if member.config.Name == name {
+			return member.service
+		}
+	}
+	return nil
+}
+
+// Run starts the services of the fleet, every service once the services it depends on are running, and starts them again according to their restart policies.
+// It blocks until all services have stopped, and returns their errors. A service is not started if a service it depends on stopped before it was running.
+// (note: on an interrupt signal every service stops by itself, call Stop to stop the services in reverse dependency order)
+func (f *Fleet) Run() error {
+	f.mutex.Lock()
+	if f.started {
+		f.mutex.Unlock()
+		return fmt.Errorf("%w: fleet", ErrAlreadyStarted)
+	}
+	f.started = true
+	f.mutex.Unlock()
+
+	waitgroup := &sync.WaitGroup{}
+	for _, member := range f.services {
+		waitgroup.Add(1)
+		go func(member *fleetMember) {
+			defer waitgroup.Done()
+			defer close(member.done)
+			member.err = f.supervise(member)
+		}(member)
+	}
+	waitgroup.Wait()
+
+	var errs []error
+	for _, member := range f.services {
+		if member.err != nil {
+			errs = append(errs, member.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// supervise starts the service once its dependencies are running, and starts it again according to its restart policy until the fleet is stopped.
+func (f *Fleet) supervise(member *fleetMember) error {
+	for _, dependency := range member.dependencies {
+		select {
+		case <-dependency.running:
+		case <-dependency.done:
+			return fmt.Errorf("%s: dependency %s stopped before it was running", member.config.Name, dependency.config.Name)
+		case <-f.stopped:
+			return nil
+		}
+	}
+
+	service := member.service
+	for {
+		select {
+		case <-f.stopped:
+			return nil
+		default:
+		}
+		err := StartRunnable(service, member.runnable)
+
+		// Restart starts the service again on its own goroutine, so wait until it stopped for good (or is stopped by the fleet)
+		if err == nil && service.GetStopReason() == STOP_REASON_RESTART {
+			ticker := time.NewTicker(50 * time.Millisecond)
+			stopped := f.stopped
+			for service.GetState() != STATE_STOPPED || (stopped != nil && service.GetStopReason() == STOP_REASON_RESTART) {
+				select {
+				case <-stopped:
+					stopped = nil // the fleet stops the service, from now on only its state is polled
+				case <-ticker.C:
+				}
+			}
+			ticker.Stop()
+		}
+
+		if !member.config.Restart.shouldRestart(service.GetStopReason(), err) {
+			return err
+		}
+		if service.GetLogLevel() >= LOG_LEVEL_WARN {
+			log.Printf("Restarting service %s in %v (restart policy %s, stop reason %s)\n", member.config.Name, member.restartDelay, member.config.Restart, service.GetStopReason())
+		}
+		timer := clockOf(service).NewTimer(member.restartDelay)
+		select {
+		case <-timer.C():
+		case <-f.stopped:
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// Stop stops the services of the fleet in reverse start order, every service after the services depending on it have stopped.
+// Services are not started again after Stop, and Run returns once all services have stopped.
+func (f *Fleet) Stop() error {
+	f.once.Do(func() {
+		close(f.stopped)
+	})
+	f.mutex.Lock()
+	started := f.started
+	f.mutex.Unlock()
+	if !started {
+		return nil
+	}
+
+	var errs []error
+	for i := len(f.services) - 1; i >= 0; i-- {
+		member := f.services[i]
+		for isStopped := false; !isStopped; {
+			err := member.service.Stop()
+			if err != nil && !errors.Is(err, ErrNotRunning) {
+				errs = append(errs, err)
+				<-member.done
+				break
+			}
+			// a service that is not running yet may be about to start, so it is stopped again until its supervisor is done
+			select {
+			case <-member.done:
+				isStopped = true
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
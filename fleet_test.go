@@ -0,0 +1,382 @@
+package ggservice_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+// fleetWorker records when it starts and stops, it fails its first runs and stops slowly if configured to.
+type fleetWorker struct {
+	name      string
+	events    *fleetEvents
+	fails     int
+	stopDelay time.Duration
+}
+
+type fleetEvents struct {
+	events []string
+	mutex  sync.Mutex
+}
+
+func (e *fleetEvents) add(event string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *fleetEvents) get() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string(nil), e.events...)
+}
+
+func (w *fleetWorker) Start() error {
+	w.events.add("start " + w.name)
+	return nil
+}
+
+func (w *fleetWorker) Run(ctx context.Context) error {
+	if w.fails > 0 {
+		w.fails--
+		return errors.New("run failed")
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (w *fleetWorker) Stop() error {
+	time.Sleep(w.stopDelay)
+	w.events.add("stop " + w.name)
+	return nil
+}
+
+func newFleetRegistry(t *testing.T, events *fleetEvents) *ggservice.Registry {
+	registry := ggservice.NewRegistry()
+	err := registry.Register("worker", func(service *ggservice.Service, config json.RawMessage) (ggservice.Runnable, error) {
+		var workerConfig struct {
+			Fails     int    `json:"fails"`
+			StopDelay string `json:"stop_delay"`
+		}
+		if config != nil {
+			err := json.Unmarshal(config, &workerConfig)
+			if err != nil {
+				return nil, err
+			}
+		}
+		worker := &fleetWorker{name: service.GetName(), events: events, fails: workerConfig.Fails}
+		if workerConfig.StopDelay != "" {
+			var err error
+			worker.stopDelay, err = time.ParseDuration(workerConfig.StopDelay)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return worker, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := newFleetRegistry(t, &fleetEvents{})
+	if registry.Register("worker", func(*ggservice.Service, json.RawMessage) (ggservice.Runnable, error) { return nil, nil }) == nil {
+		t.Error("expected error registering an implementation twice")
+	}
+	if registry.Register("", func(*ggservice.Service, json.RawMessage) (ggservice.Runnable, error) { return nil, nil }) == nil {
+		t.Error("expected error registering an implementation without name")
+	}
+}
+
+func TestParseFleetConfig(t *testing.T) {
+	tests := []struct {
+		config   string
+		expected string
+	}{
+		{"{\n\t\"services\": [\n\t\t{\"name\": \"a\", \"implementation\": \"worker\", \"grace\": \"5s\"}\n\t]\n}", `json: unknown field "grace"`},
+		{"{\n\t\"services\": [\n\t\t{\"name\": \"a\", \"restart\": \"sometimes\"}\n\t]\n}", `unknown restart policy "sometimes"`},
+		{"{\n\t\"services\": [\n\t\t{\"name\": 1}\n\t]\n}", "line 3:"},
+		{"{\n\t\"services\": [\n\t\t{\"name\": \"a\",}\n\t]\n}", "line 3: invalid character"},
+		{"{\"services\": []}\n{}", "line 2: unexpected data"},
+	}
+	for _, test := range tests {
+		_, err := ggservice.ParseFleetConfig([]byte(test.config))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected error containing %q, got %v", test.expected, err)
+		}
+	}
+}
+
+func TestNewFleet(t *testing.T) {
+	t.Run("Validation", func(t *testing.T) {
+		disabled := false
+		config := &ggservice.FleetConfig{Services: []ggservice.ServiceConfig{
+			{Name: "a", Implementation: "worker"},
+			{Name: "a", Implementation: "worker"},
+			{Name: "", Implementation: "worker"},
+			{Name: "b", Implementation: "mailer"},
+			{Name: "c", Implementation: "worker", GracefulShutdownTime: "-1s", LogLevel: "verbose", RestartDelay: "soon"},
+			{Name: "d", Implementation: "worker", DependsOn: []string{"x", "e"}},
+			{Name: "e", Implementation: "worker", Enabled: &disabled},
+		}}
+		_, err := ggservice.NewFleet(config, newFleetRegistry(t, &fleetEvents{}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		for _, expected := range []string{
+			`service "a": the name is used by another service`,
+			"services[2]: the name of a service can not be empty",
+			`service "b": unknown implementation "mailer"`,
+			`service "c": restart_delay`,
+			`service "c": graceful_shutdown_time: negative graceful shutdown time`,
+			`service "c": log_level: unknown log level`,
+			`service "d": depends on unknown service "x"`,
+			`service "d": depends on disabled service "e"`,
+		} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("expected error containing %q, got:\n%v", expected, err)
+			}
+		}
+	})
+
+	t.Run("Dependency cycle", func(t *testing.T) {
+		config := &ggservice.FleetConfig{Services: []ggservice.ServiceConfig{
+			{Name: "a", Implementation: "worker", DependsOn: []string{"b"}},
+			{Name: "b", Implementation: "worker", DependsOn: []string{"c"}},
+			{Name: "c", Implementation: "worker", DependsOn: []string{"a"}},
+		}}
+		_, err := ggservice.NewFleet(config, newFleetRegistry(t, &fleetEvents{}))
+		if err == nil || !strings.Contains(err.Error(), "dependency cycle: a -> b -> c -> a") {
+			t.Errorf("expected dependency cycle error, got %v", err)
+		}
+	})
+
+	t.Run("Factories", func(t *testing.T) {
+		events := &fleetEvents{}
+		var closed []string
+		registry := newFleetRegistry(t, events)
+		err := registry.Register("database", func(service *ggservice.Service, config json.RawMessage) (ggservice.Runnable, error) {
+			events.add("open " + service.GetName())
+			service.RegisterCloser("connection", closer{name: service.GetName(), closed: &closed})
+			if string(config) == `"unreachable"` {
+				return nil, errors.New("connection refused")
+			}
+			return &fleetWorker{name: service.GetName(), events: events}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the factories are not called for an invalid config, so they open nothing
+		for _, services := range [][]ggservice.ServiceConfig{
+			{{Name: "a", Implementation: "database"}, {Name: "b", Implementation: "mailer"}},
+			{{Name: "a", Implementation: "database", DependsOn: []string{"b"}}, {Name: "b", Implementation: "database", DependsOn: []string{"a"}}},
+		} {
+			_, err := ggservice.NewFleet(&ggservice.FleetConfig{Services: services}, registry)
+			if err == nil {
+				t.Error("expected error")
+			}
+		}
+		if len(events.get()) != 0 {
+			t.Errorf("expected no factory calls for an invalid config, got %v", events.get())
+		}
+
+		// a failing factory closes what the other factories opened
+		_, err = ggservice.NewFleet(&ggservice.FleetConfig{Services: []ggservice.ServiceConfig{
+			{Name: "b", Implementation: "database", DependsOn: []string{"a"}, Config: json.RawMessage(`"unreachable"`)},
+			{Name: "a", Implementation: "database"},
+		}}, registry)
+		if err == nil || !strings.Contains(err.Error(), `service "b": implementation "database": connection refused`) {
+			t.Errorf("expected the error of the factory, got %v", err)
+		}
+		if strings.Join(events.get(), ",") != "open a,open b" || strings.Join(closed, ",") != "b,a" {
+			t.Errorf("expected the factories to be called in start order and closed in reverse order, got %v and %v", events.get(), closed)
+		}
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		config := &ggservice.FleetConfig{Services: []ggservice.ServiceConfig{
+			{Name: "a", Implementation: "worker", GracefulShutdownTime: "10s", RunSleepDuration: "1s", LogLevel: "warn"},
+		}}
+		fleet, err := ggservice.NewFleet(config, newFleetRegistry(t, &fleetEvents{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		service := fleet.Service("a")
+		if service.GetGracefulShutdownTime() != 10*time.Second || service.GetRunSleepDuration() != time.Second || service.GetLogLevel() != ggservice.LOG_LEVEL_WARN {
+			t.Error("expected the config to set the settings of the service")
+		}
+	})
+}
+
+func TestFleet(t *testing.T) {
+	t.Run("Dependency order", func(t *testing.T) {
+		config := []byte(`{
+			"services": [
+				{"name": "api", "implementation": "worker", "log_level": "none", "depends_on": ["database", "cache"]},
+				{"name": "cache", "implementation": "worker", "log_level": "none", "depends_on": ["database"]},
+				{"name": "database", "implementation": "worker", "log_level": "none"},
+				{"name": "mailer", "implementation": "worker", "enabled": false}
+			]
+		}`)
+		path := filepath.Join(t.TempDir(), "fleet.json")
+		err := os.WriteFile(path, config, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := &fleetEvents{}
+		fleet, err := ggservice.LoadFleet(path, newFleetRegistry(t, events))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fleet.Services()) != 3 || fleet.Service("mailer") != nil {
+			t.Fatalf("expected the disabled service to be left out, got %d services", len(fleet.Services()))
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- fleet.Run()
+		}()
+		for _, service := range fleet.Services() {
+			for service.GetState() != ggservice.STATE_RUNNING {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		err = fleet.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = <-done
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := "start database,start cache,start api,stop api,stop cache,stop database"
+		if strings.Join(events.get(), ",") != expected {
+			t.Errorf("expected %s, got %v", expected, events.get())
+		}
+	})
+
+	t.Run("Restart policy", func(t *testing.T) {
+		config := &ggservice.FleetConfig{Services: []ggservice.ServiceConfig{
+			{Name: "a", Implementation: "worker", LogLevel: "none", Restart: ggservice.RESTART_POLICY_ON_FAILURE, RestartDelay: "1ms", Config: json.RawMessage(`{"fails": 2}`)},
+			{Name: "b", Implementation: "worker", LogLevel: "none", Config: json.RawMessage(`{"fails": 1}`)},
+		}}
+		events := &fleetEvents{}
+		fleet, err := ggservice.NewFleet(config, newFleetRegistry(t, events))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- fleet.Run()
+		}()
+		service := fleet.Service("a")
+		for strings.Count(strings.Join(events.get(), ","), "start a") < 3 || service.GetState() != ggservice.STATE_RUNNING {
+			time.Sleep(time.Millisecond)
+		}
+		err = fleet.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = <-done
+		var stopErr *ggservice.StopError
+		if !errors.As(err, &stopErr) || stopErr.Service != "b" || stopErr.Reason != ggservice.STOP_REASON_RUN_ERROR {
+			t.Errorf("expected only the run error of the service without restart policy, got %v", err)
+		}
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		config := &ggservice.FleetConfig{Services: []ggservice.ServiceConfig{
+			{Name: "a", Implementation: "worker", LogLevel: "none"},
+			{Name: "b", Implementation: "worker", LogLevel: "none", DependsOn: []string{"a"}, Config: json.RawMessage(`{"stop_delay": "200ms"}`)},
+		}}
+		events := &fleetEvents{}
+		fleet, err := ggservice.NewFleet(config, newFleetRegistry(t, events))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- fleet.Run()
+		}()
+		for _, service := range fleet.Services() {
+			ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, 5*time.Second)
+		}
+
+		// the supervisor of a waits for the restarted service, also while the fleet stops b slowly
+		restarted := make(chan error, 1)
+		go func() {
+			restarted <- fleet.Service("a").Restart() // this is a blocking call
+		}()
+		for strings.Count(strings.Join(events.get(), ","), "start a") < 2 || fleet.Service("a").GetState() != ggservice.STATE_RUNNING {
+			time.Sleep(time.Millisecond)
+		}
+		err = fleet.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = <-done
+		if err != nil {
+			t.Error(err)
+		}
+		err = <-restarted
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := "start a,start b,stop a,start a,stop b,stop a"
+		if strings.Join(events.get(), ",") != expected {
+			t.Errorf("expected %s, got %v", expected, events.get())
+		}
+	})
+
+	t.Run("Failed dependency", func(t *testing.T) {
+		registry := newFleetRegistry(t, &fleetEvents{})
+		err := registry.Register("broken", func(*ggservice.Service, json.RawMessage) (ggservice.Runnable, error) {
+			return brokenWorker{}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := &ggservice.FleetConfig{Services: []ggservice.ServiceConfig{
+			{Name: "database", Implementation: "broken", LogLevel: "none"},
+			{Name: "api", Implementation: "worker", LogLevel: "none", DependsOn: []string{"database"}},
+		}}
+		fleet, err := ggservice.NewFleet(config, registry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fleet.Run()
+		if err == nil || !strings.Contains(err.Error(), "api: dependency database stopped before it was running") {
+			t.Errorf("expected dependency error, got %v", err)
+		}
+		if fleet.Service("api").GetState() != ggservice.STATE_STOPPED {
+			t.Error("expected the service depending on the failed service not to start")
+		}
+	})
+}
+
+// brokenWorker fails to start.
+type brokenWorker struct{}
+
+func (brokenWorker) Start() error {
+	return errors.New("start failed")
+}
+
+func (brokenWorker) Run(ctx context.Context) error {
+	return nil
+}
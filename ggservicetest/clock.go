@@ -0,0 +1,105 @@
+package ggservicetest
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+// FakeClock is a clock that only moves when Advance is called, so tests do not depend on real time.
+// Set it on a service with ggservice.WithClock to drive the run sleep duration, the graceful shutdown time and the shutdown hook timeout.
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer // Pending timers
+	mutex  sync.Mutex
+}
+
+// NewFakeClock creates a new fake clock starting at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer that fires once the clock is advanced by the given duration.
+func (c *FakeClock) NewTimer(duration time.Duration) ggservice.Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	timer := &fakeTimer{clock: c, at: c.now.Add(duration), c: make(chan time.Time, 1)}
+	if duration <= 0 {
+		timer.c <- c.now
+		return timer
+	}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// Advance moves the clock forward by the given duration, and fires the timers that are due in order.
+func (c *FakeClock) Advance(duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(duration)
+
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].at.Before(c.timers[j].at)
+	})
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- timer.at
+	}
+	c.timers = pending
+}
+
+// Timers returns the amount of pending timers.
+func (c *FakeClock) Timers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.timers)
+}
+
+// AwaitTimers waits until at least the given amount of timers are pending, so Advance fires them.
+// The test fails if that takes longer than the timeout (in real time).
+func (c *FakeClock) AwaitTimers(t testing.TB, timers int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for c.Timers() < timers {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d pending timers within %v, got %d", timers, timeout, c.Timers())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// fakeTimer is a timer of a FakeClock.
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time // Time at which the timer fires
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,316 @@
+package ggservicetest
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+// conformanceTimeout is how long the conformance tests wait for a service to reach a state (in real time).
+const conformanceTimeout = 5 * time.Second
+
+// conformanceLogLevels are the log levels the behavior of a service must not depend on.
+var conformanceLogLevels = []int{ggservice.LOG_LEVEL_NONE, ggservice.LOG_LEVEL_ERROR, ggservice.LOG_LEVEL_WARN, ggservice.LOG_LEVEL_INFO, ggservice.LOG_LEVEL_ALL}
+
+// RunConformance runs the lifecycle, concurrency and timing tests of the IService contract against services created by the factory,
+// for example to test decorators of IService or other implementations. The factory must return a new service that is not started.
+//
+// The contract:
+//   - SetLogLevel accepts every log level and SetRunSleepDuration a positive duration, the suite fails if they return an error
+//   - Stop and a second Start return an error when the service is not running or already started, at every log level
+//   - Start calls the start function, then the run function until the service stops, then the stop function (also when start or run fail), and returns their errors
+//   - the state goes from STATE_STARTING to STATE_RUNNING to STATE_STOPPING to STATE_STOPPED, and GetIsRunning is only true while running
+//   - Restart stops the service and starts it again with the same functions, the first Start returns
+//   - Stop interrupts the run sleep duration, and the stop function runs after the in-flight run function has finished
+//   - the methods can be called from any goroutine, and only one of concurrent Stop calls succeeds
+func RunConformance(t *testing.T, factory func() ggservice.IService) {
+	t.Run("Stop before Start", func(t *testing.T) {
+		for _, logLevel := range conformanceLogLevels {
+			service := factory()
+			mustSet(t, service.SetLogLevel(logLevel))
+			if service.Stop() == nil {
+				t.Errorf("log level %d: expected Stop before Start to return an error", logLevel)
+			}
+			if service.GetState() != ggservice.STATE_STOPPED || service.GetIsRunning() {
+				t.Errorf("log level %d: expected service to stay stopped, got %s", logLevel, service.GetState())
+			}
+		}
+	})
+
+	t.Run("Start without functions", func(t *testing.T) {
+		service := factory()
+		mustSet(t, service.SetLogLevel(ggservice.LOG_LEVEL_NONE))
+		err := service.Start(nil, nil, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		AssertStopped(t, service)
+	})
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		for _, logLevel := range conformanceLogLevels {
+			service := factory()
+			mustSet(t, service.SetLogLevel(logLevel))
+			mustSet(t, service.SetRunSleepDuration(time.Millisecond))
+
+			var calls []string
+			var mutex sync.Mutex
+			record := func(call string) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				if len(calls) == 0 || calls[len(calls)-1] != call {
+					calls = append(calls, call)
+				}
+			}
+			done := StartAsync(service, func() error {
+				record("start")
+				return nil
+			}, func() error {
+				record("run")
+				return nil
+			}, func() error {
+				record("stop")
+				return nil
+			}, nil)
+
+			AwaitState(t, service, ggservice.STATE_RUNNING, conformanceTimeout)
+			if !service.GetIsRunning() {
+				t.Errorf("log level %d: expected running service to report it is running", logLevel)
+			}
+			err := service.Stop()
+			if err != nil {
+				t.Errorf("log level %d: %v", logLevel, err)
+			}
+			err = awaitStart(t, done)
+			if err != nil {
+				t.Errorf("log level %d: %v", logLevel, err)
+			}
+			AssertStopped(t, service)
+			if service.Stop() == nil {
+				t.Errorf("log level %d: expected second Stop to return an error", logLevel)
+			}
+
+			mutex.Lock()
+			if len(calls) != 3 || calls[0] != "start" || calls[1] != "run" || calls[2] != "stop" {
+				t.Errorf("log level %d: expected start, run and stop function in order, got %v", logLevel, calls)
+			}
+			mutex.Unlock()
+		}
+	})
+
+	t.Run("Double Start", func(t *testing.T) {
+		for _, logLevel := range conformanceLogLevels {
+			service := factory()
+			mustSet(t, service.SetLogLevel(logLevel))
+			starts := atomic.Int32{}
+			startFunc := func() error {
+				starts.Add(1)
+				return nil
+			}
+			done := StartAsync(service, startFunc, func() error { return nil }, nil, nil)
+			AwaitState(t, service, ggservice.STATE_RUNNING, conformanceTimeout)
+
+			err := service.Start(startFunc, func() error { return nil }, nil, nil)
+			if err == nil {
+				t.Errorf("log level %d: expected second Start to return an error", logLevel)
+			}
+			if starts.Load() != 1 || service.GetState() != ggservice.STATE_RUNNING {
+				t.Errorf("log level %d: expected second Start to leave the running service alone, got %d starts and %s", logLevel, starts.Load(), service.GetState())
+			}
+			_ = service.Stop()
+			_ = awaitStart(t, done)
+		}
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		service := factory()
+		mustSet(t, service.SetLogLevel(ggservice.LOG_LEVEL_NONE))
+		starts, stops := atomic.Int32{}, atomic.Int32{}
+		done := StartAsync(service, func() error {
+			starts.Add(1)
+			return nil
+		}, func() error {
+			return nil
+		}, func() error {
+			stops.Add(1)
+			return nil
+		}, nil)
+		AwaitState(t, service, ggservice.STATE_RUNNING, conformanceTimeout)
+
+		restarted := make(chan error, 1)
+		go func() {
+			restarted <- service.Restart()
+		}()
+		err := awaitStart(t, done)
+		if err != nil {
+			t.Error(err)
+		}
+		deadline := time.Now().Add(conformanceTimeout)
+		for starts.Load() < 2 || service.GetState() != ggservice.STATE_RUNNING {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected service to run again after Restart, got %d starts and %s", starts.Load(), service.GetState())
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		err = service.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = awaitStart(t, restarted)
+		if err != nil {
+			t.Error(err)
+		}
+		AssertStopped(t, service)
+		if starts.Load() != 2 || stops.Load() != 2 {
+			t.Errorf("expected 2 starts and 2 stops, got %d starts and %d stops", starts.Load(), stops.Load())
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		startErr := errors.New("start failed")
+		runErr := errors.New("run failed")
+
+		service := factory()
+		mustSet(t, service.SetLogLevel(ggservice.LOG_LEVEL_NONE))
+		isStopped := false
+		err := service.Start(func() error {
+			return startErr
+		}, func() error {
+			t.Error("expected run function not to be called after the start function failed")
+			return nil
+		}, func() error {
+			isStopped = true
+			return nil
+		}, nil)
+		if !errors.Is(err, startErr) {
+			t.Errorf("expected start error, got %v", err)
+		}
+		if !isStopped {
+			t.Error("expected stop function to run after the start function failed")
+		}
+		AssertStopped(t, service)
+
+		service = factory()
+		mustSet(t, service.SetLogLevel(ggservice.LOG_LEVEL_NONE))
+		err = service.Start(nil, func() error {
+			return runErr
+		}, nil, nil)
+		if !errors.Is(err, runErr) {
+			t.Errorf("expected run error, got %v", err)
+		}
+		AssertStopped(t, service)
+	})
+
+	t.Run("Stop interrupts sleep", func(t *testing.T) {
+		service := factory()
+		mustSet(t, service.SetLogLevel(ggservice.LOG_LEVEL_NONE))
+		mustSet(t, service.SetRunSleepDuration(time.Hour))
+
+		var isRunFuncDone, isStopFuncAfterRun atomic.Bool
+		runs := make(chan struct{}, 1)
+		done := StartAsync(service, nil, func() error {
+			select {
+			case runs <- struct{}{}:
+			default:
+			}
+			time.Sleep(10 * time.Millisecond) // the in-flight run function finishes before the stop function runs
+			isRunFuncDone.Store(true)
+			return nil
+		}, func() error {
+			isStopFuncAfterRun.Store(isRunFuncDone.Load())
+			return nil
+		}, nil)
+
+		<-runs
+		startTime := time.Now()
+		err := service.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = awaitStart(t, done)
+		if err != nil {
+			t.Error(err)
+		}
+		if elapsed := time.Since(startTime); elapsed >= conformanceTimeout {
+			t.Errorf("expected Stop to interrupt the run sleep duration, took %v", elapsed)
+		}
+		if !isStopFuncAfterRun.Load() {
+			t.Error("expected stop function to run after the in-flight run function finished")
+		}
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		service := factory()
+		mustSet(t, service.SetLogLevel(ggservice.LOG_LEVEL_NONE))
+		done := StartAsync(service, nil, func() error { return nil }, nil, nil)
+
+		stop := make(chan struct{})
+		readers := sync.WaitGroup{}
+		for range 4 {
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						_ = service.GetState()
+						_ = service.GetIsRunning()
+						_ = service.GetName()
+					}
+				}
+			}()
+		}
+		AwaitState(t, service, ggservice.STATE_RUNNING, conformanceTimeout)
+
+		stopped := atomic.Int32{}
+		stoppers := sync.WaitGroup{}
+		for range 10 {
+			stoppers.Add(1)
+			go func() {
+				defer stoppers.Done()
+				if service.Stop() == nil {
+					stopped.Add(1)
+				}
+			}()
+		}
+		stoppers.Wait()
+		err := awaitStart(t, done)
+		close(stop)
+		readers.Wait()
+
+		if err != nil {
+			t.Error(err)
+		}
+		if stopped.Load() != 1 {
+			t.Errorf("expected exactly one of the concurrent Stop calls to succeed, got %d", stopped.Load())
+		}
+		AssertStopped(t, service)
+	})
+}
+
+// awaitStart waits for Start to return, and fails the test if that takes longer than the conformance timeout.
+func awaitStart(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(conformanceTimeout):
+		t.Fatalf("expected Start to return within %v", conformanceTimeout)
+		return nil
+	}
+}
+
+// mustSet fails the test if the service rejected a setting, as the suite would not test what it claims to.
+func mustSet(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("setting was rejected: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package ggservicetest_test
+
+import (
+	"testing"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+func TestRunConformance(t *testing.T) {
+	ggservicetest.RunConformance(t, func() ggservice.IService {
+		service, err := ggservice.NewService("My Service")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return service
+	})
+}
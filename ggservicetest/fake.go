@@ -0,0 +1,346 @@
+package ggservicetest
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+// Call is a recorded call of a method of a FakeService.
+type Call struct {
+	Method string // Name of the method, like "Restart"
+	Args   []any  // Arguments of the call
+}
+
+// FakeService is an IService that records every call with its arguments, for unit tests of code that takes an IService.
+// It does not run the functions passed to it: Start returns right away (unless blocked with Block), and Go, RegisterCloser and OnShutdown only record the call.
+// Getters return the value passed to the matching setter, unless a return value is scripted with Return.
+type FakeService struct {
+	name                 string
+	calls                []Call
+	results              map[string][]any           // Scripted return values by method
+	blocks               map[string]<-chan struct{} // Channels the methods block on until they are closed
+	gracefulShutdownTime time.Duration
+	runSleepDuration     time.Duration
+	minRunDelay          time.Duration
+	maxRunDelay          time.Duration
+	logLevel             int
+	pidFile              string
+	stopOnGoError        bool
+	shutdownHookTimeout  time.Duration
+	logShutdownReport    bool
+	clock                ggservice.Clock
+	mutex                sync.Mutex
+}
+
+// NewFakeService creates a new fake service with the given name.
+func NewFakeService(name string) *FakeService {
+	return &FakeService{
+		name:    name,
+		results: map[string][]any{},
+		blocks:  map[string]<-chan struct{}{},
+	}
+}
+
+// Return scripts the values the method returns, in the order of its results. (for example Return("Restart", err) or Return("GetState", ggservice.STATE_RUNNING))
+func (f *FakeService) Return(method string, values ...any) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.results[method] = values
+}
+
+// Block makes the method block until the given channel is closed. The call is recorded before it blocks.
+func (f *FakeService) Block(method string, unblock <-chan struct{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.blocks[method] = unblock
+}
+
+// Calls returns the recorded calls in the order they were made.
+func (f *FakeService) Calls() []Call {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Methods returns the names of the methods of the recorded calls in the order they were made.
+func (f *FakeService) Methods() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	methods := make([]string, 0, len(f.calls))
+	for _, call := range f.calls {
+		methods = append(methods, call.Method)
+	}
+	return methods
+}
+
+// Reset removes the recorded calls, scripted return values and blocks.
+func (f *FakeService) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls = nil
+	f.results = map[string][]any{}
+	f.blocks = map[string]<-chan struct{}{}
+}
+
+// call records the call, blocks if the method is blocked and returns the scripted return values.
+func (f *FakeService) call(method string, args ...any) []any {
+	f.mutex.Lock()
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+	results := f.results[method]
+	unblock := f.blocks[method]
+	f.mutex.Unlock()
+
+	if unblock != nil {
+		<-unblock
+	}
+	return results
+}
+
+// result returns the scripted return value at the index, or the fallback if no value of the type was scripted.
+func result[T any](results []any, index int, fallback T) T {
+	if index < len(results) {
+		value, ok := results[index].(T)
+		if ok {
+			return value
+		}
+	}
+	return fallback
+}
+
+func (f *FakeService) Start(startFunc func() error, runFunc func() error, stopFunc func() error, forceShutdownFunc func() error) error {
+	return result[error](f.call("Start", startFunc, runFunc, stopFunc, forceShutdownFunc), 0, nil)
+}
+
+func (f *FakeService) StartScheduled(startFunc func() error, runFunc func(ctx context.Context) (time.Duration, error), stopFunc func() error, forceShutdownFunc func() error) error {
+	return result[error](f.call("StartScheduled", startFunc, runFunc, stopFunc, forceShutdownFunc), 0, nil)
+}
+
+func (f *FakeService) Reload() error {
+	return result[error](f.call("Reload"), 0, nil)
+}
+
+func (f *FakeService) HealthCheck(ctx context.Context) error {
+	return result[error](f.call("HealthCheck", ctx), 0, nil)
+}
+
+func (f *FakeService) Restart() error {
+	return result[error](f.call("Restart"), 0, nil)
+}
+
+func (f *FakeService) Stop() error {
+	return result[error](f.call("Stop"), 0, nil)
+}
+
+func (f *FakeService) ForceShutdown() error {
+	return result[error](f.call("ForceShutdown"), 0, nil)
+}
+
+func (f *FakeService) GetIsRunning() bool {
+	return result(f.call("GetIsRunning"), 0, false)
+}
+
+func (f *FakeService) GetName() string {
+	return result(f.call("GetName"), 0, f.name)
+}
+
+func (f *FakeService) GetState() ggservice.State {
+	return result(f.call("GetState"), 0, ggservice.STATE_STOPPED)
+}
+
+func (f *FakeService) GetGracefulShutdownTime() time.Duration {
+	results := f.call("GetGracefulShutdownTime")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.gracefulShutdownTime)
+}
+
+func (f *FakeService) SetGracefulShutdownTime(gracefulShutdownTime time.Duration) error {
+	results := f.call("SetGracefulShutdownTime", gracefulShutdownTime)
+	err := result[error](results, 0, nil)
+	if err != nil {
+		return err // a scripted error rejects the value
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.gracefulShutdownTime = gracefulShutdownTime
+	return nil
+}
+
+func (f *FakeService) GetRunSleepDuration() time.Duration {
+	results := f.call("GetRunSleepDuration")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.runSleepDuration)
+}
+
+func (f *FakeService) SetRunSleepDuration(runSleepDuration time.Duration) error {
+	results := f.call("SetRunSleepDuration", runSleepDuration)
+	err := result[error](results, 0, nil)
+	if err != nil {
+		return err // a scripted error rejects the value
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.runSleepDuration = runSleepDuration
+	return nil
+}
+
+func (f *FakeService) GetRunDelayBounds() (time.Duration, time.Duration) {
+	results := f.call("GetRunDelayBounds")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.minRunDelay), result(results, 1, f.maxRunDelay)
+}
+
+func (f *FakeService) SetRunDelayBounds(minDelay time.Duration, maxDelay time.Duration) error {
+	results := f.call("SetRunDelayBounds", minDelay, maxDelay)
+	err := result[error](results, 0, nil)
+	if err != nil {
+		return err // a scripted error rejects the value
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.minRunDelay, f.maxRunDelay = minDelay, maxDelay
+	return nil
+}
+
+func (f *FakeService) GetLogLevel() int {
+	results := f.call("GetLogLevel")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.logLevel)
+}
+
+func (f *FakeService) SetLogLevel(logLevel int) error {
+	results := f.call("SetLogLevel", logLevel)
+	err := result[error](results, 0, nil)
+	if err != nil {
+		return err // a scripted error rejects the value
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.logLevel = logLevel
+	return nil
+}
+
+func (f *FakeService) GetPIDFile() string {
+	results := f.call("GetPIDFile")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.pidFile)
+}
+
+func (f *FakeService) SetPIDFile(pidFile string) error {
+	results := f.call("SetPIDFile", pidFile)
+	err := result[error](results, 0, nil)
+	if err != nil {
+		return err // a scripted error rejects the value
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.pidFile = pidFile
+	return nil
+}
+
+func (f *FakeService) Go(fn func(ctx context.Context) error) {
+	f.call("Go", fn)
+}
+
+func (f *FakeService) GetStopOnGoError() bool {
+	results := f.call("GetStopOnGoError")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.stopOnGoError)
+}
+
+func (f *FakeService) SetStopOnGoError(stopOnGoError bool) {
+	f.call("SetStopOnGoError", stopOnGoError)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.stopOnGoError = stopOnGoError
+}
+
+func (f *FakeService) RegisterCloser(name string, closer io.Closer) {
+	f.call("RegisterCloser", name, closer)
+}
+
+func (f *FakeService) OnShutdown(shutdownFunc func(ctx context.Context) error) {
+	f.call("OnShutdown", shutdownFunc)
+}
+
+func (f *FakeService) GetShutdownHookTimeout() time.Duration {
+	results := f.call("GetShutdownHookTimeout")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.shutdownHookTimeout)
+}
+
+func (f *FakeService) SetShutdownHookTimeout(shutdownHookTimeout time.Duration) error {
+	results := f.call("SetShutdownHookTimeout", shutdownHookTimeout)
+	err := result[error](results, 0, nil)
+	if err != nil {
+		return err // a scripted error rejects the value
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.shutdownHookTimeout = shutdownHookTimeout
+	return nil
+}
+
+func (f *FakeService) GetStopReason() ggservice.StopReason {
+	return result(f.call("GetStopReason"), 0, ggservice.STOP_REASON_NONE)
+}
+
+func (f *FakeService) GetExitCode() int {
+	return result(f.call("GetExitCode"), 0, 0)
+}
+
+func (f *FakeService) SetExitCode(reason ggservice.StopReason, exitCode int) {
+	f.call("SetExitCode", reason, exitCode)
+}
+
+func (f *FakeService) GetShutdownReport() *ggservice.ShutdownReport {
+	return result[*ggservice.ShutdownReport](f.call("GetShutdownReport"), 0, nil)
+}
+
+func (f *FakeService) GetLogShutdownReport() bool {
+	results := f.call("GetLogShutdownReport")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.logShutdownReport)
+}
+
+func (f *FakeService) SetLogShutdownReport(logShutdownReport bool) {
+	f.call("SetLogShutdownReport", logShutdownReport)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.logShutdownReport = logShutdownReport
+}
+
+func (f *FakeService) GetClock() ggservice.Clock {
+	results := f.call("GetClock")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return result(results, 0, f.clock)
+}
+
+func (f *FakeService) SetClock(clock ggservice.Clock) error {
+	results := f.call("SetClock", clock)
+	err := result[error](results, 0, nil)
+	if err != nil {
+		return err // a scripted error rejects the value
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.clock = clock
+	return nil
+}
+
+func (f *FakeService) Signal(sig os.Signal) {
+	f.call("Signal", sig)
+}
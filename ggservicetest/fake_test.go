@@ -0,0 +1,76 @@
+package ggservicetest_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+// restartHandler is an example of code that takes an IService.
+func restartHandler(service ggservice.IService) error {
+	if service.GetState() != ggservice.STATE_RUNNING {
+		return errors.New(service.GetName() + " is not running")
+	}
+	return service.Restart()
+}
+
+func TestFakeService(t *testing.T) {
+	t.Run("Records calls", func(t *testing.T) {
+		var service ggservice.IService = ggservicetest.NewFakeService("My Service")
+		service.SetGracefulShutdownTime(time.Minute)
+		if service.GetGracefulShutdownTime() != time.Minute {
+			t.Errorf("expected graceful shutdown time of a minute, got %v", service.GetGracefulShutdownTime())
+		}
+
+		err := restartHandler(service)
+		if err == nil {
+			t.Error("expected error, as the service is not running")
+		}
+		calls := service.(*ggservicetest.FakeService).Calls()
+		if calls[0].Method != "SetGracefulShutdownTime" || calls[0].Args[0] != time.Minute {
+			t.Errorf("unexpected first call: %+v", calls[0])
+		}
+		expected := []string{"SetGracefulShutdownTime", "GetGracefulShutdownTime", "GetState", "GetName"}
+		if methods := service.(*ggservicetest.FakeService).Methods(); !reflect.DeepEqual(methods, expected) {
+			t.Errorf("expected %v, got %v", expected, methods)
+		}
+	})
+
+	t.Run("Scripted results", func(t *testing.T) {
+		service := ggservicetest.NewFakeService("My Service")
+		restartErr := errors.New("restart failed")
+		service.Return("GetState", ggservice.STATE_RUNNING)
+		service.Return("Restart", restartErr)
+
+		err := restartHandler(service)
+		if !errors.Is(err, restartErr) {
+			t.Errorf("expected restart error, got %v", err)
+		}
+		expected := []string{"GetState", "Restart"}
+		if !reflect.DeepEqual(service.Methods(), expected) {
+			t.Errorf("expected %v, got %v", expected, service.Methods())
+		}
+	})
+
+	t.Run("Blocking", func(t *testing.T) {
+		service := ggservicetest.NewFakeService("My Service")
+		unblock := make(chan struct{})
+		service.Block("Start", unblock)
+
+		done := ggservicetest.StartAsync(service, nil, nil, nil, nil)
+		select {
+		case <-done:
+			t.Fatal("expected Start to block")
+		case <-time.After(10 * time.Millisecond):
+		}
+		close(unblock)
+		err := <-done
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+// Package ggservicetest provides a fake clock, simulated signals and lifecycle assertions for testing services built on ggservice.
+package ggservicetest
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+// StartAsync starts the service on a new goroutine, and returns a channel that receives the error of Start once the service stopped.
+func StartAsync(service ggservice.IService, startFunc func() error, runFunc func() error, stopFunc func() error, forceShutdownFunc func() error) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- service.Start(startFunc, runFunc, stopFunc, forceShutdownFunc)
+	}()
+	return done
+}
+
+// Interrupt delivers a simulated SIGTERM to the service, without signalling the process.
+// The service shuts down gracefully, and is forced to shut down if the graceful shutdown time elapses.
+// It panics if the service can not receive simulated signals (like *ggservice.Service and FakeService can).
+func Interrupt(service ggservice.IService) {
+	signaler, ok := service.(interface{ Signal(sig os.Signal) })
+	if !ok {
+		panic("ggservicetest: " + service.GetName() + " can not receive simulated signals")
+	}
+	signaler.Signal(syscall.SIGTERM)
+}
+
+// AwaitState waits until the service is in the given state.
+// The test fails if that takes longer than the timeout (in real time).
+func AwaitState(t testing.TB, service ggservice.IService, state ggservice.State, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for service.GetState() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s to be %s within %v, got %s", service.GetName(), state, timeout, service.GetState())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// AssertStopped fails the test if the service is not stopped.
+func AssertStopped(t testing.TB, service ggservice.IService) {
+	t.Helper()
+	if service.GetState() != ggservice.STATE_STOPPED || service.GetIsRunning() {
+		t.Errorf("expected %s to be stopped, got %s", service.GetName(), service.GetState())
+	}
+}
@@ -0,0 +1,104 @@
+package ggservicetest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+func TestFakeClock(t *testing.T) {
+	clock := ggservicetest.NewFakeClock(time.Unix(0, 0))
+	service, err := ggservice.NewService("My Service",
+		ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE),
+		ggservice.WithClock(clock),
+		ggservice.WithRunSleepDuration(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := make(chan struct{}, 10)
+	done := ggservicetest.StartAsync(service, nil, func() error {
+		runs <- struct{}{}
+		return nil
+	}, nil, nil)
+
+	<-runs
+	clock.AwaitTimers(t, 1, time.Second) // the run loop sleeps for an hour
+	clock.Advance(59 * time.Minute)
+	select {
+	case <-runs:
+		t.Fatal("expected the run loop to sleep for an hour")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Minute)
+	<-runs
+
+	ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, time.Second)
+	ggservicetest.Interrupt(service)
+	err = <-done
+	if err != nil {
+		t.Error(err)
+	}
+	ggservicetest.AssertStopped(t, service)
+	if service.GetStopReason() != ggservice.STOP_REASON_SIGNAL {
+		t.Errorf("expected stop reason %s, got %s", ggservice.STOP_REASON_SIGNAL, service.GetStopReason())
+	}
+	if clock.Now() != time.Unix(0, 0).Add(time.Hour) {
+		t.Errorf("unexpected time: %v", clock.Now())
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	t.Run("Forced shutdown", func(t *testing.T) {
+		clock := ggservicetest.NewFakeClock(time.Unix(0, 0))
+		service, err := ggservice.NewService("My Service",
+			ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE),
+			ggservice.WithClock(clock),
+			ggservice.WithGracefulShutdownTime(time.Minute),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		isForced := make(chan struct{})
+		release := make(chan struct{})
+		done := ggservicetest.StartAsync(service, nil, func() error {
+			<-release // an in-flight run function that does not finish in time
+			return nil
+		}, nil, func() error {
+			close(isForced)
+			return nil
+		})
+
+		ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, time.Second)
+		ggservicetest.Interrupt(service)
+		ggservicetest.AwaitState(t, service, ggservice.STATE_STOPPING, time.Second)
+		clock.Advance(time.Minute)
+		select {
+		case <-isForced:
+		case <-time.After(time.Second):
+			t.Fatal("expected forced shutdown after the graceful shutdown time")
+		}
+		if service.GetStopReason() != ggservice.STOP_REASON_FORCE_TIMEOUT {
+			t.Errorf("expected stop reason %s, got %s", ggservice.STOP_REASON_FORCE_TIMEOUT, service.GetStopReason())
+		}
+
+		close(release)
+		<-done
+		ggservicetest.AssertStopped(t, service)
+	})
+
+	t.Run("Without simulated signals", func(t *testing.T) {
+		// a decorator that only implements IService can not receive simulated signals
+		service := struct{ ggservice.IService }{ggservicetest.NewFakeService("My Service")}
+		defer func() {
+			if recover() == nil {
+				t.Error("expected Interrupt to panic")
+			}
+		}()
+		ggservicetest.Interrupt(service)
+	})
+}
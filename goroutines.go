@@ -0,0 +1,101 @@
+package ggservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/pprof"
+	"sync"
+)
+
+// Go runs the function on a new goroutine tracked by the service, like errgroup.Group.Go.
+// The context is cancelled when the service stops, and the service waits for its goroutines (up to the graceful shutdown time)
+// before the stop function runs. Errors of the goroutines are logged, passed to the OnError hooks and returned by Start.
+// If SetStopOnGoError is set, the first error stops the service. Go called on a service that is not started runs fn with a cancelled context.
+func (s *Service) Go(fn func(ctx context.Context) error) {
+	s.mutex.Lock()
+	ctx := s.ctx
+	goroutines := s.goroutines
+	if goroutines == nil {
+		goroutines = &sync.WaitGroup{}
+	}
+	goroutines.Add(1)
+	s.mutex.Unlock()
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+	}
+
+	go pprof.Do(ctx, s.pprofLabels(pprofPhaseGoroutine), func(ctx context.Context) {
+		defer goroutines.Done()
+		err := callGo(ctx, fn)
+		if err == nil || (errors.Is(err, context.Canceled) && ctx.Err() != nil) {
+			return // returning the context error after the service stopped is not a failure
+		}
+
+		s.mutex.Lock()
+		s.goErrors = append(s.goErrors, err)
+		s.mutex.Unlock()
+		if s.GetLogLevel() >= LOG_LEVEL_ERROR {
+			log.Printf("%s: goroutine failed: %v\n", s.Name, err)
+		}
+		s.emit(PHASE_ERROR, err)
+		if s.stopOnGoError.Load() && ctx.Err() == nil {
+			_ = s.stop(STOP_REASON_GO_ERROR)
+		}
+	})
+}
+
+func (s *Service) GetStopOnGoError() bool {
+	return s.stopOnGoError.Load()
+}
+
+// SetStopOnGoError makes the service stop when a goroutine started with Go returns an error. (default: false)
+func (s *Service) SetStopOnGoError(stopOnGoError bool) {
+	s.stopOnGoError.Store(stopOnGoError)
+}
+
+// callGo calls the goroutine function and turns a panic into an error.
+func callGo(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			err = fmt.Errorf("goroutine panicked: %v", recovered)
+		}
+	}()
+	return fn(ctx)
+}
+
+// waitGoroutines waits up to the graceful shutdown time for the goroutines started with Go, and returns their joined errors.
+func (s *Service) waitGoroutines() error {
+	s.mutex.Lock()
+	goroutines := s.goroutines
+	s.mutex.Unlock()
+
+	var timeoutErr error
+	if goroutines != nil {
+		done := make(chan struct{})
+		s.goLabeled(pprofPhaseStop, func() {
+			goroutines.Wait()
+			close(done)
+		})
+		timer := s.GetClock().NewTimer(s.GetGracefulShutdownTime())
+		select {
+		case <-done:
+			timer.Stop()
+		case <-timer.C():
+			timeoutErr = fmt.Errorf("timed out after %v waiting for goroutines", s.GetGracefulShutdownTime())
+			if s.GetLogLevel() >= LOG_LEVEL_WARN {
+				log.Printf("%s: %v\n", s.Name, timeoutErr)
+			}
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	err := errors.Join(append(s.goErrors, timeoutErr)...)
+	s.goErrors = nil
+	return err
+}
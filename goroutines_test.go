@@ -0,0 +1,81 @@
+package ggservice_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestService_Go(t *testing.T) {
+	t.Run("Cancelled and awaited on stop", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		var isFlushed atomic.Bool
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			_ = service.Stop()
+		}()
+		err := service.Start(func() error {
+			service.Go(func(ctx context.Context) error {
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond) // flushing after cancellation
+				isFlushed.Store(true)
+				return ctx.Err()
+			})
+			return nil
+		}, func() error {
+			time.Sleep(time.Millisecond)
+			return nil
+		}, func() error {
+			if !isFlushed.Load() {
+				t.Error("expected goroutine to finish before the stop function")
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Error stops service", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		service.SetStopOnGoError(true)
+
+		goErr := errors.New("refresh failed")
+		err := service.Start(func() error {
+			service.Go(func(ctx context.Context) error {
+				return goErr
+			})
+			return nil
+		}, func() error {
+			time.Sleep(time.Millisecond)
+			return nil
+		}, nil, nil)
+		if !errors.Is(err, goErr) {
+			t.Errorf("expected goroutine error, got %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		service.SetGracefulShutdownTime(10 * time.Millisecond)
+
+		err := service.Start(func() error {
+			service.Go(func(ctx context.Context) error {
+				time.Sleep(time.Second) // ignores the context
+				return nil
+			})
+			return nil
+		}, nil, nil, nil)
+		if err == nil {
+			t.Error("expected timeout error")
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package ggservice
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Phase identifies a lifecycle event of a service.
+type Phase int
+
+// Lifecycle phases
+const (
+	PHASE_STARTING Phase = iota // 0: Start was called, the start function is about to run
+	PHASE_RUNNING               // 1: The start function completed
+	PHASE_STOPPING              // 2: Stop was called
+	PHASE_STOPPED               // 3: The service stopped (Err is set if it stopped because of an error)
+	PHASE_ERROR                 // 4: The start, run or stop function returned an error
+	PHASE_RESTART               // 5: Restart was called
+	phaseCount
+)
+
+// String returns the lowercase name of the phase.
+func (phase Phase) String() string {
+	switch phase {
+	case PHASE_STARTING:
+		return "starting"
+	case PHASE_RUNNING:
+		return "running"
+	case PHASE_STOPPING:
+		return "stopping"
+	case PHASE_STOPPED:
+		return "stopped"
+	case PHASE_ERROR:
+		return "error"
+	case PHASE_RESTART:
+		return "restart"
+	}
+	return "unknown"
+}
+
+// Event describes a lifecycle event of a service, passed to the hooks registered for its phase.
+type Event struct {
+	Service    string        // Name of the service
+	Phase      Phase         // Phase the service entered
+	Time       time.Time     // Time of the event
+	Duration   time.Duration // Time since the previous phase (for PHASE_RUNNING the duration of the start function)
+	Err        error         // Error for PHASE_ERROR, and for PHASE_STOPPED when the service stopped because of an error
+	StopReason StopReason    // Why the service stops, for PHASE_STOPPING and PHASE_STOPPED
+}
+
+// Hooks is the registry of lifecycle hooks of a service, multiple hooks can be registered for every phase.
+//
+// Hooks are called synchronously on the goroutine causing the event, in the order they were registered, and the lifecycle
+// waits for them (for example the start function does not run before the PHASE_STARTING hooks have returned).
+// Wrap a hook with AsyncHook to call it on its own goroutine instead. A panicking hook is recovered and logged,
+// it never breaks the lifecycle or prevents the other hooks from being called.
+type Hooks struct {
+	hooks [phaseCount][]func(Event)
+	mutex sync.RWMutex
+}
+
+// OnStarting registers a hook called when Start is called, before the start function runs.
+func (h *Hooks) OnStarting(hook func(Event)) {
+	h.on(PHASE_STARTING, hook)
+}
+
+// OnRunning registers a hook called when the start function has completed.
+func (h *Hooks) OnRunning(hook func(Event)) {
+	h.on(PHASE_RUNNING, hook)
+}
+
+// OnStopping registers a hook called when Stop is called on a started service.
+func (h *Hooks) OnStopping(hook func(Event)) {
+	h.on(PHASE_STOPPING, hook)
+}
+
+// OnStopped registers a hook called when the service has stopped, also when it stopped because of an error.
+func (h *Hooks) OnStopped(hook func(Event)) {
+	h.on(PHASE_STOPPED, hook)
+}
+
+// OnError registers a hook called when the start, run or stop function returns an error.
+func (h *Hooks) OnError(hook func(Event)) {
+	h.on(PHASE_ERROR, hook)
+}
+
+// OnRestart registers a hook called when Restart is called.
+func (h *Hooks) OnRestart(hook func(Event)) {
+	h.on(PHASE_RESTART, hook)
+}
+
+func (h *Hooks) on(phase Phase, hook func(Event)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.hooks[phase] = append(h.hooks[phase], hook)
+}
+
+// call calls the hooks registered for the phase of the event.
+func (h *Hooks) call(event Event, logLevel int) {
+	h.mutex.RLock()
+	hooks := h.hooks[event.Phase]
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		callHook(hook, event, logLevel)
+	}
+}
+
+// callHook calls a single hook and recovers it from panicking.
+func callHook(hook func(Event), event Event, logLevel int) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil && logLevel >= LOG_LEVEL_ERROR {
+			log.Printf("%s: %s hook panicked: %v\n", event.Service, event.Phase, recovered)
+		}
+	}()
+	hook(event)
+}
+
+// AsyncHook wraps a hook, so it is called on its own goroutine and the lifecycle does not wait for it.
+// A panic in the hook is recovered and logged.
+func AsyncHook(hook func(Event)) func(Event) {
+	return func(event Event) {
+		go callHook(hook, event, LOG_LEVEL_ERROR)
+	}
+}
+
+// emit calls the hooks for the phase with the time since the previous phase.
+func (s *Service) emit(phase Phase, err error) {
+	now := time.Now()
+	previous := s.phaseStartedAt.Load()
+	duration := time.Duration(0)
+	if previous != 0 && phase != PHASE_STARTING {
+		duration = now.Sub(time.Unix(0, previous))
+	}
+	// errors and restarts happen within a phase, they do not start a new one
+	if phase != PHASE_ERROR && phase != PHASE_RESTART {
+		s.phaseStartedAt.Store(now.UnixNano())
+	}
+
+	event := Event{
+		Service:    s.Name,
+		Phase:      phase,
+		Time:       now,
+		Duration:   duration,
+		Err:        err,
+		StopReason: s.GetStopReason(),
+	}
+	s.traceEvent(event)
+	s.hooks.call(event, s.GetLogLevel())
+}
@@ -0,0 +1,98 @@
+package ggservice_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestService_Hooks(t *testing.T) {
+	t.Run("Run error", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		var phases []ggservice.Phase
+		var stoppedErr error
+		record := func(event ggservice.Event) {
+			if event.Service != "My Service" {
+				t.Errorf("unexpected service name: %s", event.Service)
+			}
+			phases = append(phases, event.Phase)
+		}
+		service.Hooks().OnStarting(func(event ggservice.Event) {
+			panic("a panicking hook must not break the lifecycle")
+		})
+		service.Hooks().OnStarting(record)
+		service.Hooks().OnRunning(record)
+		service.Hooks().OnError(record)
+		service.Hooks().OnStopped(record)
+		service.Hooks().OnStopped(func(event ggservice.Event) {
+			stoppedErr = event.Err
+		})
+
+		runErr := errors.New("run failed")
+		err := service.Start(nil, func() error {
+			return runErr
+		}, nil, nil)
+		if !errors.Is(err, runErr) {
+			t.Errorf("expected run error, got %v", err)
+		}
+
+		expected := []ggservice.Phase{ggservice.PHASE_STARTING, ggservice.PHASE_RUNNING, ggservice.PHASE_ERROR, ggservice.PHASE_STOPPED}
+		if !reflect.DeepEqual(phases, expected) {
+			t.Errorf("expected phases %v, got %v", expected, phases)
+		}
+		if !errors.Is(stoppedErr, runErr) {
+			t.Errorf("expected stopped event with run error, got %v", stoppedErr)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		stopping := make(chan ggservice.Event, 1)
+		stopped := make(chan ggservice.Event, 1)
+		service.Hooks().OnStopping(ggservice.AsyncHook(func(event ggservice.Event) {
+			stopping <- event
+		}))
+		service.Hooks().OnStopped(func(event ggservice.Event) {
+			stopped <- event
+		})
+
+		waitgroup := sync.WaitGroup{}
+		waitgroup.Add(1)
+		go func() {
+			defer waitgroup.Done()
+			err := service.Start(nil, func() error {
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			}, nil, nil)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+		for i := 0; i < 100 && service.GetState() != ggservice.STATE_RUNNING; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		err := service.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		waitgroup.Wait()
+
+		event := <-stopping
+		if event.Phase != ggservice.PHASE_STOPPING || event.Duration <= 0 {
+			t.Errorf("unexpected stopping event: %+v", event)
+		}
+		event = <-stopped
+		if event.Phase != ggservice.PHASE_STOPPED || event.Err != nil {
+			t.Errorf("unexpected stopped event: %+v", event)
+		}
+	})
+}
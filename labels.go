@@ -0,0 +1,41 @@
+package ggservice
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Values of the "phase" pprof label of the goroutines of a service
+const (
+	pprofPhaseStart         = "start"          // The start function
+	pprofPhaseRun           = "run"            // The run function
+	pprofPhaseStop          = "stop"           // The stop function, and waiting for goroutines
+	pprofPhaseShutdown      = "shutdown"       // Closers and shutdown functions
+	pprofPhaseGoroutine     = "goroutine"      // Goroutines started with Go
+	pprofPhaseSignal        = "signal"         // Listening for interrupt signals
+	pprofPhaseForceShutdown = "force shutdown" // Waiting for the graceful shutdown time to force a shutdown
+)
+
+// pprofLabels returns the pprof labels of the service in the given phase.
+func (s *Service) pprofLabels(phase string) pprof.LabelSet {
+	return pprof.Labels("service", s.Name, "phase", phase)
+}
+
+// traced calls fn as a runtime/trace task, with the goroutine labeled with the service and phase for pprof.
+// The labels are restored when fn returns, and goroutines started by fn inherit them.
+func (s *Service) traced(ctx context.Context, phase string, fn func(ctx context.Context)) {
+	pprof.Do(ctx, s.pprofLabels(phase), func(ctx context.Context) {
+		ctx, task := trace.NewTask(ctx, "ggservice."+phase)
+		defer task.End()
+		trace.Log(ctx, "service", s.Name)
+		fn(ctx)
+	})
+}
+
+// goLabeled runs fn on a new goroutine labeled with the service and phase for pprof.
+func (s *Service) goLabeled(phase string, fn func()) {
+	go pprof.Do(context.Background(), s.pprofLabels(phase), func(ctx context.Context) {
+		fn()
+	})
+}
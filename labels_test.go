@@ -0,0 +1,40 @@
+package ggservice_test
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestService_pprofLabels(t *testing.T) {
+	service := newService(t, "My Service")
+	service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+	labels := map[string]string{}
+	goroutineLabels := make(chan string, 1)
+	err := service.StartScheduled(func() error {
+		service.Go(func(ctx context.Context) error {
+			phase, _ := pprof.Label(ctx, "phase")
+			goroutineLabels <- phase
+			return nil
+		})
+		return nil
+	}, func(ctx context.Context) (time.Duration, error) {
+		labels["service"], _ = pprof.Label(ctx, "service")
+		labels["phase"], _ = pprof.Label(ctx, "phase")
+		return 0, ggservice.ErrStopService
+	}, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if labels["service"] != "My Service" || labels["phase"] != "run" {
+		t.Errorf("expected run function to be labeled with service and phase, got %v", labels)
+	}
+	if phase := <-goroutineLabels; phase != "goroutine" {
+		t.Errorf("expected goroutine phase label, got %q", phase)
+	}
+}
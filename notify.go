@@ -0,0 +1,167 @@
+package ggservice
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const notifierPollInterval = 100 * time.Millisecond
+
+// SdNotify sends a state like "READY=1" to systemd using the socket in NOTIFY_SOCKET. (see sd_notify(3))
+// It does nothing when the program is not started by systemd with Type=notify.
+func SdNotify(state string) error {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+
+	// a socket path starting with @ is an abstract socket, which net handles for us
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns the watchdog timeout systemd expects pings within, or 0 if the watchdog is not enabled for this process.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	pid := os.Getenv("WATCHDOG_PID")
+	if pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Notifier keeps systemd informed about a set of services (Type=notify units).
+// It sends READY=1 once every service has completed its start function, keeps STATUS= updated with a summary of the
+// service states and pings WATCHDOG=1 at half of WATCHDOG_USEC as long as no run function hangs for longer than the watchdog timeout.
+// STOPPING=1 is sent by the services themselves when they receive an interrupt signal.
+// Outside systemd (NOTIFY_SOCKET is not set) the notifier only reports ready to an upgrading parent process (see UpgradeReady).
+type Notifier struct {
+	services []IService
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewNotifier creates a new systemd notifier for the given services.
+func NewNotifier(services ...IService) *Notifier {
+	return &Notifier{
+		services: services,
+		done:     make(chan struct{}),
+	}
+}
+
+// Run notifies systemd until Close is called. (note: this is a blocking call)
+// A failed notification is logged and sent again on the next poll, so a hiccup of systemd does not stop the watchdog pings.
+func (n *Notifier) Run() error {
+	watchdogTimeout := watchdogInterval()
+	var lastWatchdogPing time.Time
+	isReady := false
+	isUpgradeReady := false
+	lastStatus := ""
+
+	ticker := time.NewTicker(notifierPollInterval)
+	defer ticker.Stop()
+	for {
+		var states []string
+		status := n.status()
+		if status != lastStatus {
+			states = append(states, "STATUS="+status)
+		}
+		isStarted := n.isStarted()
+		if !isReady && isStarted {
+			states = append(states, "READY=1")
+		}
+		if !isUpgradeReady && isStarted {
+			err := UpgradeReady() // does nothing when the process was not started by an upgrade
+			if err != nil {
+				log.Println("reporting ready to the upgrading process failed: " + err.Error())
+			}
+			isUpgradeReady = true // UpgradeReady only reports once, so there is no retry
+		}
+		isWatchdogPing := watchdogTimeout > 0 && time.Since(lastWatchdogPing) >= watchdogTimeout/2 && n.isProgressing(watchdogTimeout)
+		if isWatchdogPing {
+			states = append(states, "WATCHDOG=1")
+		}
+		if len(states) > 0 {
+			err := SdNotify(strings.Join(states, "\n"))
+			if err != nil {
+				log.Println("notifying systemd failed: " + err.Error())
+			} else {
+				lastStatus = status
+				isReady = isReady || isStarted
+				if isWatchdogPing {
+					lastWatchdogPing = time.Now()
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-n.done:
+			return nil
+		}
+	}
+}
+
+// Close stops the notifier.
+func (n *Notifier) Close() error {
+	n.once.Do(func() {
+		close(n.done)
+	})
+	return nil
+}
+
+// isStarted reports whether the start function of every service has completed.
+func (n *Notifier) isStarted() bool {
+	for _, service := range n.services {
+		if service.GetState() != STATE_RUNNING {
+			return false
+		}
+	}
+	return true
+}
+
+// isProgressing reports whether no run function has been running for longer than the watchdog timeout.
+func (n *Notifier) isProgressing(watchdogTimeout time.Duration) bool {
+	for _, service := range n.services {
+		runFunc, ok := service.(interface{ runFuncDuration() time.Duration })
+		if ok && runFunc.runFuncDuration() > watchdogTimeout {
+			return false
+		}
+	}
+	return true
+}
+
+// status summarizes the states of the services, like "2/3 services running (My Service 3: stopping)".
+func (n *Notifier) status() string {
+	running := 0
+	var others []string
+	for _, service := range n.services {
+		state := service.GetState()
+		if state == STATE_RUNNING {
+			running++
+		} else {
+			others = append(others, service.GetName()+": "+state.String())
+		}
+	}
+
+	status := fmt.Sprintf("%d/%d services running", running, len(n.services))
+	if len(others) > 0 {
+		status += " (" + strings.Join(others, ", ") + ")"
+	}
+	return status
+}
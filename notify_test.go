@@ -0,0 +1,127 @@
+package ggservice_test
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestSdNotify(t *testing.T) {
+	t.Run("Without NOTIFY_SOCKET", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		err := ggservice.SdNotify("READY=1")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("With NOTIFY_SOCKET", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "notify.sock")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		t.Setenv("NOTIFY_SOCKET", socketPath)
+
+		err = ggservice.SdNotify("READY=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		buffer := make([]byte, 1024)
+		n, err := conn.Read(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buffer[:n]) != "READY=1" {
+			t.Errorf("unexpected notification: %q", buffer[:n])
+		}
+	})
+}
+
+func TestNotifier(t *testing.T) {
+	t.Run("Notifications", func(t *testing.T) {
+		testNotifier(t, false)
+	})
+
+	t.Run("Failed notifications", func(t *testing.T) {
+		// systemd is not listening at first, the notifier must keep trying instead of giving up
+		testNotifier(t, true)
+	})
+}
+
+func testNotifier(t *testing.T, isListeningLate bool) {
+	socketPath := filepath.Join(t.TempDir(), "notify.sock")
+	t.Setenv("NOTIFY_SOCKET", socketPath)
+	t.Setenv("WATCHDOG_USEC", "200000")
+
+	service := newService(t, "My Service")
+	service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+	notifier := ggservice.NewNotifier(service)
+
+	listen := func() *net.UnixConn {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	var conn *net.UnixConn
+	if !isListeningLate {
+		conn = listen()
+	}
+
+	waitgroup := sync.WaitGroup{}
+	waitgroup.Add(2)
+	go func() {
+		defer waitgroup.Done()
+		err := notifier.Run()
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer waitgroup.Done()
+		err := service.Start(nil, func() error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if isListeningLate {
+		for service.GetState() != ggservice.STATE_RUNNING {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(300 * time.Millisecond) // the notifier fails to send READY=1 and the first watchdog pings
+		conn = listen()
+	}
+	defer conn.Close()
+
+	received := map[string]bool{}
+	buffer := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for !received["READY=1"] || !received["WATCHDOG=1"] || !received["STATUS=1/1 services running"] {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			t.Fatalf("%v (received: %v)", err, received)
+		}
+		for _, state := range strings.Split(string(buffer[:n]), "\n") {
+			received[state] = true
+		}
+	}
+
+	err := service.Stop()
+	if err != nil {
+		t.Error(err)
+	}
+	_ = notifier.Close()
+	waitgroup.Wait()
+}
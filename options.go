@@ -0,0 +1,80 @@
+package ggservice
+
+import "time"
+
+// Option configures a service created by NewService, it returns an error if its value is invalid.
+// The options use the setters of the service, so they are validated the same way.
+type Option func(service *Service) error
+
+// WithGracefulShutdownTime sets for how long the service may shut down before it is forced to. (see SetGracefulShutdownTime)
+func WithGracefulShutdownTime(gracefulShutdownTime time.Duration) Option {
+	return func(service *Service) error {
+		return service.SetGracefulShutdownTime(gracefulShutdownTime)
+	}
+}
+
+// WithRunSleepDuration sets for how long the run loop sleeps between runs. (see SetRunSleepDuration)
+func WithRunSleepDuration(runSleepDuration time.Duration) Option {
+	return func(service *Service) error {
+		return service.SetRunSleepDuration(runSleepDuration)
+	}
+}
+
+// WithRunDelayBounds bounds the delay between runs. (see SetRunDelayBounds)
+func WithRunDelayBounds(minDelay time.Duration, maxDelay time.Duration) Option {
+	return func(service *Service) error {
+		return service.SetRunDelayBounds(minDelay, maxDelay)
+	}
+}
+
+// WithLogLevel sets which messages the service logs. (see SetLogLevel)
+func WithLogLevel(logLevel int) Option {
+	return func(service *Service) error {
+		return service.SetLogLevel(logLevel)
+	}
+}
+
+// WithPIDFile makes the service write and lock a PID file. (see SetPIDFile)
+func WithPIDFile(pidFile string) Option {
+	return func(service *Service) error {
+		return service.SetPIDFile(pidFile)
+	}
+}
+
+// WithStopOnGoError makes the service stop when a goroutine started with Go fails. (see SetStopOnGoError)
+func WithStopOnGoError(stopOnGoError bool) Option {
+	return func(service *Service) error {
+		service.SetStopOnGoError(stopOnGoError)
+		return nil
+	}
+}
+
+// WithShutdownHookTimeout sets for how long every closer and shutdown function may run. (see SetShutdownHookTimeout)
+func WithShutdownHookTimeout(shutdownHookTimeout time.Duration) Option {
+	return func(service *Service) error {
+		return service.SetShutdownHookTimeout(shutdownHookTimeout)
+	}
+}
+
+// WithExitCode sets the exit code for a stop reason. (see SetExitCode)
+func WithExitCode(reason StopReason, exitCode int) Option {
+	return func(service *Service) error {
+		service.SetExitCode(reason, exitCode)
+		return nil
+	}
+}
+
+// WithLogShutdownReport makes the service log its shutdown report. (see SetLogShutdownReport)
+func WithLogShutdownReport(logShutdownReport bool) Option {
+	return func(service *Service) error {
+		service.SetLogShutdownReport(logShutdownReport)
+		return nil
+	}
+}
+
+// WithClock sets the clock the timers of the service are created with. (see SetClock)
+func WithClock(clock Clock) Option {
+	return func(service *Service) error {
+		return service.SetClock(clock)
+	}
+}
@@ -0,0 +1,87 @@
+package ggservice
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errLocked is returned by lockFile when another process holds the lock.
+var errLocked = errors.New("file is locked by another process")
+
+// AlreadyRunningError is returned by Start when another live instance of the program holds the lock on the PID file.
+type AlreadyRunningError struct {
+	PIDFile string // Path of the PID file
+	PID     int    // Process id of the running instance, 0 if it could not be read
+}
+
+func (e *AlreadyRunningError) Error() string {
+	if e.PID == 0 {
+		return "another instance is already running (pid file: " + e.PIDFile + ")"
+	}
+	return "another instance is already running with pid " + strconv.Itoa(e.PID) + " (pid file: " + e.PIDFile + ")"
+}
+
+// acquirePIDFile locks the PID file and writes the process id to it.
+// A PID file left behind by a crashed instance is not locked anymore (the lock is released with the process), so it is taken over.
+func acquirePIDFile(path string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		err = lockFile(file)
+		if errors.Is(err, errLocked) {
+			content, _ := os.ReadFile(path)
+			pid, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+			_ = file.Close()
+			return nil, &AlreadyRunningError{PIDFile: path, PID: pid}
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+
+		// another instance may have removed the file between opening and locking it, then the lock is on a removed file
+		openedInfo, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		pathInfo, err := os.Stat(path)
+		if err != nil || !os.SameFile(openedInfo, pathInfo) {
+			_ = file.Close()
+			continue
+		}
+
+		err = file.Truncate(0)
+		if err == nil {
+			_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+		}
+		if err == nil {
+			err = file.Sync()
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+}
+
+// releasePIDFile removes the PID file and releases its lock, if the service holds one.
+func (s *Service) releasePIDFile() {
+	if s.pidFileHandle == nil {
+		return
+	}
+	// the file is removed while it is still locked, so no other instance can lock it before it is gone
+	err := os.Remove(s.pidFileHandle.Name())
+	if err != nil && s.GetLogLevel() >= LOG_LEVEL_WARN {
+		log.Println("Could not remove pid file: " + err.Error())
+	}
+	_ = s.pidFileHandle.Close() // closing the file releases the lock
+	s.pidFileHandle = nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package ggservice
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is not supported on this platform.
+func lockFile(file *os.File) error {
+	return errors.New("pid file locking is not supported on this platform")
+}
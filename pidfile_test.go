@@ -0,0 +1,139 @@
+package ggservice_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestService_SetPIDFile(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "service.pid")
+
+	// a pid file left behind by a crashed instance is not locked and must be taken over
+	err := os.WriteFile(pidFile, []byte("999999\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := newService(t, "My Service")
+	service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+	service.SetPIDFile(pidFile)
+
+	waitgroup := sync.WaitGroup{}
+	waitgroup.Add(1)
+	go func() {
+		defer waitgroup.Done()
+		err := service.Start(nil, func() error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	for i := 0; i < 100 && service.GetState() != ggservice.STATE_RUNNING; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	content, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(content)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("expected pid file to contain %d, got %q", os.Getpid(), content)
+	}
+
+	t.Run("Second instance", func(t *testing.T) {
+		second := newService(t, "My Service")
+		second.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		second.SetPIDFile(pidFile)
+		err := second.Start(nil, nil, nil, nil)
+		var alreadyRunning *ggservice.AlreadyRunningError
+		if !errors.As(err, &alreadyRunning) {
+			t.Fatalf("expected an AlreadyRunningError, got %v", err)
+		}
+		if alreadyRunning.PID != os.Getpid() {
+			t.Errorf("expected pid %d, got %d", os.Getpid(), alreadyRunning.PID)
+		}
+		if second.GetIsRunning() || second.GetState() == ggservice.STATE_RUNNING {
+			t.Error("expected the second instance not to be started")
+		}
+		err = second.SetPIDFile(pidFile)
+		if err != nil {
+			t.Errorf("expected the PID file to be changeable after a failed start, got %v", err)
+		}
+	})
+
+	err = service.Stop()
+	if err != nil {
+		t.Error(err)
+	}
+	waitgroup.Wait()
+
+	_, err = os.Stat(pidFile)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected pid file to be removed, got %v", err)
+	}
+}
+
+func TestService_SetPIDFile_retry(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "service.pid")
+	holder := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE), ggservice.WithPIDFile(pidFile))
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- holder.Start(nil, func() error {
+			<-release
+			return ggservice.ErrStopService
+		}, nil, nil)
+	}()
+	for i := 0; i < 100 && holder.GetState() != ggservice.STATE_RUNNING; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE), ggservice.WithPIDFile(pidFile))
+	run := func() error {
+		return ggservice.ErrStopService
+	}
+
+	// the lock is held by another instance, a retry succeeds once it is released
+	var alreadyRunning *ggservice.AlreadyRunningError
+	err := service.Start(nil, run, nil, nil)
+	if !errors.As(err, &alreadyRunning) {
+		t.Fatalf("expected an AlreadyRunningError, got %v", err)
+	}
+	close(release)
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = service.Start(nil, run, nil, nil)
+	if err != nil {
+		t.Errorf("expected the retry to start the service, got %v", err)
+	}
+
+	// the PID file can not be opened, a retry succeeds once it is fixed
+	err = service.SetPIDFile(filepath.Join(t.TempDir(), "missing", "service.pid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = service.Start(nil, run, nil, nil)
+	if err == nil || errors.Is(err, ggservice.ErrAlreadyStarted) {
+		t.Fatalf("expected the PID file to fail to open, got %v", err)
+	}
+	err = service.SetPIDFile(pidFile)
+	if err != nil {
+		t.Fatalf("expected the PID file to be changeable after a failed start, got %v", err)
+	}
+	err = service.Start(nil, run, nil, nil)
+	if err != nil {
+		t.Errorf("expected the retry to start the service, got %v", err)
+	}
+}
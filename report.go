@@ -0,0 +1,186 @@
+package ggservice
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// ShutdownReport tells how long every part of the last shutdown of a service took.
+type ShutdownReport struct {
+	Service            string         // Name of the service
+	Reason             StopReason     // Why the service stopped
+	RequestedAt        time.Time      // Time the stop was requested (Stop, a signal or an upgrade), zero if the service stopped by itself
+	StoppingAt         time.Time      // Time the run loop ended, so the in-flight run function had finished
+	RunFuncDuration    time.Duration  // Time from the stop request until the in-flight run function finished
+	GoroutinesDuration time.Duration  // Time waiting for the goroutines started with Go
+	StopFuncDuration   time.Duration  // Duration of the stop function
+	Closers            []CloserReport // Closers and shutdown functions in the order they ran
+	Duration           time.Duration  // Total time from the stop request (or the end of the run loop) until the service stopped
+	Forced             bool           // Whether the graceful shutdown time elapsed and a forced shutdown was triggered
+	Err                error          // Error the service stopped with
+}
+
+// CloserReport tells how long a closer or shutdown function took.
+type CloserReport struct {
+	Name     string        // Name the closer was registered with
+	Duration time.Duration // Duration of the closer
+	Err      error         // Error of the closer, also when it timed out
+}
+
+// MarshalJSON writes the report with durations and errors as strings, so it can be logged.
+func (r ShutdownReport) MarshalJSON() ([]byte, error) {
+	type closerJSON struct {
+		Name     string `json:"name"`
+		Duration string `json:"duration"`
+		Err      string `json:"error,omitempty"`
+	}
+	closers := make([]closerJSON, 0, len(r.Closers))
+	for _, closer := range r.Closers {
+		closers = append(closers, closerJSON{Name: closer.Name, Duration: closer.Duration.String(), Err: errorString(closer.Err)})
+	}
+	var requestedAt *time.Time
+	if !r.RequestedAt.IsZero() {
+		requestedAt = &r.RequestedAt
+	}
+
+	return json.Marshal(struct {
+		Service            string       `json:"service"`
+		Reason             string       `json:"reason"`
+		RequestedAt        *time.Time   `json:"requestedAt,omitempty"`
+		StoppingAt         time.Time    `json:"stoppingAt"`
+		RunFuncDuration    string       `json:"runFuncDuration"`
+		GoroutinesDuration string       `json:"goroutinesDuration"`
+		StopFuncDuration   string       `json:"stopFuncDuration"`
+		Closers            []closerJSON `json:"closers"`
+		Duration           string       `json:"duration"`
+		Forced             bool         `json:"forced"`
+		Err                string       `json:"error,omitempty"`
+	}{
+		Service:            r.Service,
+		Reason:             r.Reason.String(),
+		RequestedAt:        requestedAt,
+		StoppingAt:         r.StoppingAt,
+		RunFuncDuration:    r.RunFuncDuration.String(),
+		GoroutinesDuration: r.GoroutinesDuration.String(),
+		StopFuncDuration:   r.StopFuncDuration.String(),
+		Closers:            closers,
+		Duration:           r.Duration.String(),
+		Forced:             r.Forced,
+		Err:                errorString(r.Err),
+	})
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// GetShutdownReport returns the report of the last shutdown of the service, or nil if it has not stopped yet.
+func (s *Service) GetShutdownReport() *ShutdownReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.shutdownReport == nil {
+		return nil
+	}
+	report := *s.shutdownReport
+	report.Closers = append([]CloserReport(nil), report.Closers...)
+	return &report
+}
+
+func (s *Service) GetLogShutdownReport() bool {
+	return s.logShutdownReport.Load()
+}
+
+// SetLogShutdownReport makes the service log its shutdown report as JSON when it stops or is forced to shut down. (default: false)
+func (s *Service) SetLogShutdownReport(logShutdownReport bool) {
+	s.logShutdownReport.Store(logShutdownReport)
+}
+
+// stopRequested records the time the stop was first requested since the service started.
+func (s *Service) stopRequested() {
+	s.stopRequestedAt.CompareAndSwap(0, time.Now().UnixNano())
+}
+
+// newShutdownReport starts the report of the shutdown, when the run loop has ended.
+func (s *Service) newShutdownReport() *ShutdownReport {
+	report := &ShutdownReport{Service: s.Name, StoppingAt: time.Now()}
+	requestedAt := s.stopRequestedAt.Load()
+	if requestedAt != 0 {
+		report.RequestedAt = time.Unix(0, requestedAt)
+		report.RunFuncDuration = report.StoppingAt.Sub(report.RequestedAt)
+	}
+	s.mutex.Lock()
+	s.shutdownInProgress = report
+	s.mutex.Unlock()
+	return report
+}
+
+// updateShutdownReport changes the report of the shutdown in progress, guarded as it is read on a forced shutdown.
+func (s *Service) updateShutdownReport(update func(report *ShutdownReport)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.shutdownInProgress != nil {
+		update(s.shutdownInProgress)
+	}
+}
+
+// finishShutdownReport completes the report when the service has stopped, and makes it available with GetShutdownReport.
+func (s *Service) finishShutdownReport(report *ShutdownReport, err error) {
+	startedAt := report.StoppingAt
+	if !report.RequestedAt.IsZero() {
+		startedAt = report.RequestedAt
+	}
+
+	s.mutex.Lock()
+	report.Reason = s.GetStopReason()
+	report.Duration = time.Since(startedAt)
+	report.Forced = s.forced.Load()
+	report.Err = err
+	s.shutdownInProgress = nil
+	s.shutdownReport = report
+	s.mutex.Unlock()
+
+	s.logReport(report)
+}
+
+// forceShutdownReport marks the shutdown as forced, and logs the report so far as the program may exit before the service stopped.
+func (s *Service) forceShutdownReport() {
+	s.forced.Store(true)
+	s.mutex.Lock()
+	var report ShutdownReport
+	inProgress := s.shutdownInProgress != nil
+	if inProgress {
+		report = *s.shutdownInProgress
+		report.Closers = append([]CloserReport(nil), report.Closers...)
+	}
+	s.mutex.Unlock()
+	if !inProgress {
+		report = ShutdownReport{Service: s.Name}
+		requestedAt := s.stopRequestedAt.Load()
+		if requestedAt != 0 {
+			report.RequestedAt = time.Unix(0, requestedAt)
+		}
+	}
+
+	report.Reason = s.GetStopReason()
+	report.Forced = true
+	if !report.RequestedAt.IsZero() {
+		report.Duration = time.Since(report.RequestedAt)
+	}
+	s.logReport(&report)
+}
+
+func (s *Service) logReport(report *ShutdownReport) {
+	if !s.logShutdownReport.Load() || s.GetLogLevel() <= LOG_LEVEL_NONE {
+		return
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("%s: could not marshal shutdown report: %v\n", s.Name, err)
+		return
+	}
+	log.Printf("%s shutdown report: %s\n", s.Name, data)
+}
@@ -0,0 +1,69 @@
+package ggservice_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestService_GetShutdownReport(t *testing.T) {
+	service := newService(t, "My Service")
+	service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+	if service.GetShutdownReport() != nil {
+		t.Error("expected no shutdown report before the service stopped")
+	}
+
+	closeErr := errors.New("close failed")
+	var closed []string
+	err := service.Start(func() error {
+		service.RegisterCloser("database", closer{name: "database", closed: &closed, err: closeErr})
+		service.OnShutdown(func(ctx context.Context) error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+		return nil
+	}, func() error {
+		_ = service.Stop()
+		time.Sleep(10 * time.Millisecond) // the in-flight run function finishes after the stop request
+		return nil
+	}, func() error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}, nil)
+	if !errors.Is(err, closeErr) {
+		t.Errorf("expected close error, got %v", err)
+	}
+
+	report := service.GetShutdownReport()
+	if report == nil {
+		t.Fatal("expected shutdown report")
+	}
+	if report.Service != "My Service" || report.Reason != ggservice.STOP_REASON_STOP || report.Forced {
+		t.Errorf("unexpected shutdown report: %+v", report)
+	}
+	if report.RequestedAt.IsZero() || report.RunFuncDuration <= 0 || report.RunFuncDuration > report.Duration {
+		t.Errorf("unexpected run function duration %v of total %v", report.RunFuncDuration, report.Duration)
+	}
+	if report.StopFuncDuration < 5*time.Millisecond {
+		t.Errorf("expected stop function duration of at least 5ms, got %v", report.StopFuncDuration)
+	}
+	if len(report.Closers) != 2 || report.Closers[0].Name != "shutdown function" || report.Closers[1].Name != "database" {
+		t.Fatalf("unexpected closers: %+v", report.Closers)
+	}
+	if report.Closers[0].Duration < 5*time.Millisecond || !errors.Is(report.Closers[1].Err, closeErr) {
+		t.Errorf("unexpected closers: %+v", report.Closers)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"reason":"stop"`) || !strings.Contains(string(data), `"error":"close failed"`) {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
@@ -0,0 +1,95 @@
+package ggservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Runnable is the work of a service, an alternative to passing functions to Start. (see StartRunnable)
+// Run is called in a loop like the run function of Start, and the context is cancelled by Stop.
+// A Runnable can implement the optional interfaces Starter, Stopper, ForceStopper, Reloader and HealthChecker.
+type Runnable interface {
+	Run(ctx context.Context) error
+}
+
+// Starter is implemented by a Runnable that has to start before it runs, like the start function of Start.
+type Starter interface {
+	Start() error
+}
+
+// Stopper is implemented by a Runnable that has to stop after it ran, like the stop function of Start.
+type Stopper interface {
+	Stop() error
+}
+
+// ForceStopper is implemented by a Runnable that handles forced shutdowns itself, like the forceShutdown function of Start.
+type ForceStopper interface {
+	ForceStop() error
+}
+
+// Reloader is implemented by a Runnable that can reload its configuration without restarting. (see Service.Reload)
+type Reloader interface {
+	Reload() error
+}
+
+// HealthChecker is implemented by a Runnable that can check its health. (see Service.HealthCheck)
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// StartRunnable starts the service with the Runnable, like Start with the methods of the optional interfaces the Runnable implements.
+// Like Start, it blocks until the service stops. Reload and HealthCheck of a *Service use the Runnable while it runs.
+func StartRunnable(service IService, runnable Runnable) error {
+	var startFunc, stopFunc, forceShutdownFunc func() error
+	if starter, ok := runnable.(Starter); ok {
+		startFunc = starter.Start
+	}
+	if stopper, ok := runnable.(Stopper); ok {
+		stopFunc = stopper.Stop
+	}
+	if forceStopper, ok := runnable.(ForceStopper); ok {
+		forceShutdownFunc = forceStopper.ForceStop
+	}
+	runFunc := func(ctx context.Context) (time.Duration, error) {
+		return service.GetRunSleepDuration(), runnable.Run(ctx)
+	}
+	runnableService, ok := service.(interface {
+		startScheduled(runnable Runnable, startFunc func() error, runFunc func(ctx context.Context) (time.Duration, error), stopFunc func() error, forceShutdownFunc func() error) error
+	})
+	if !ok {
+		return service.StartScheduled(startFunc, runFunc, stopFunc, forceShutdownFunc)
+	}
+	return runnableService.startScheduled(runnable, startFunc, runFunc, stopFunc, forceShutdownFunc)
+}
+
+// Reload reloads the running service without restarting it, if it was started with a Runnable that implements Reloader.
+// It returns ErrNotSupported otherwise, so the caller can fall back to Restart.
+func (s *Service) Reload() error {
+	if !s.GetIsRunning() {
+		return fmt.Errorf("%w: %s", ErrNotRunning, s.Name)
+	}
+	reloader, ok := s.getRunnable().(Reloader)
+	if !ok {
+		return fmt.Errorf("reload %s: %w", s.Name, ErrNotSupported)
+	}
+	return reloader.Reload()
+}
+
+// HealthCheck returns an error if the service is not running, or if it was started with a Runnable that implements HealthChecker and it is not healthy.
+func (s *Service) HealthCheck(ctx context.Context) error {
+	if !s.GetIsRunning() {
+		return fmt.Errorf("%w: %s", ErrNotRunning, s.Name)
+	}
+	healthChecker, ok := s.getRunnable().(HealthChecker)
+	if !ok {
+		return nil
+	}
+	return healthChecker.HealthCheck(ctx)
+}
+
+func (s *Service) getRunnable() Runnable {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.runnable
+}
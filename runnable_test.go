@@ -0,0 +1,142 @@
+package ggservice_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+// worker only implements Runnable.
+type worker struct {
+	runs int
+}
+
+func (w *worker) Run(ctx context.Context) error {
+	w.runs++
+	if w.runs == 3 {
+		return ggservice.ErrStopService
+	}
+	return nil
+}
+
+// reloadingWorker implements Runnable and every optional interface.
+type reloadingWorker struct {
+	calls   chan string
+	healthy error
+}
+
+func (w *reloadingWorker) Start() error {
+	w.calls <- "start"
+	return nil
+}
+
+func (w *reloadingWorker) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (w *reloadingWorker) Stop() error {
+	w.calls <- "stop"
+	return nil
+}
+
+func (w *reloadingWorker) ForceStop() error {
+	return nil
+}
+
+func (w *reloadingWorker) Reload() error {
+	w.calls <- "reload"
+	return nil
+}
+
+func (w *reloadingWorker) HealthCheck(ctx context.Context) error {
+	return w.healthy
+}
+
+func TestStartRunnable(t *testing.T) {
+	t.Run("Run only", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		runnable := &worker{}
+		err := ggservice.StartRunnable(service, runnable)
+		if err != nil {
+			t.Error(err)
+		}
+		if runnable.runs != 3 {
+			t.Errorf("expected 3 runs, got %d", runnable.runs)
+		}
+		err = service.Reload()
+		if !errors.Is(err, ggservice.ErrNotRunning) {
+			t.Errorf("expected not running error, got %v", err)
+		}
+	})
+
+	t.Run("Optional interfaces", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		unhealthy := errors.New("database unreachable")
+		runnable := &reloadingWorker{calls: make(chan string, 10), healthy: unhealthy}
+		done := make(chan error, 1)
+		go func() {
+			done <- ggservice.StartRunnable(service, runnable)
+		}()
+		ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, time.Second)
+
+		err := service.Reload()
+		if err != nil {
+			t.Error(err)
+		}
+		err = service.HealthCheck(context.Background())
+		if !errors.Is(err, unhealthy) {
+			t.Errorf("expected health check error, got %v", err)
+		}
+		_ = service.Stop()
+		err = <-done
+		if err != nil {
+			t.Error(err)
+		}
+
+		close(runnable.calls)
+		var calls []string
+		for call := range runnable.calls {
+			calls = append(calls, call)
+		}
+		if len(calls) != 3 || calls[0] != "start" || calls[1] != "reload" || calls[2] != "stop" {
+			t.Errorf("expected start, reload and stop, got %v", calls)
+		}
+	})
+
+	t.Run("Other implementations", func(t *testing.T) {
+		service := ggservicetest.NewFakeService("My Service")
+		err := ggservice.StartRunnable(service, &worker{})
+		if err != nil {
+			t.Error(err)
+		}
+		if methods := service.Methods(); len(methods) != 1 || methods[0] != "StartScheduled" {
+			t.Errorf("expected the runnable to be started with StartScheduled, got %v", methods)
+		}
+	})
+
+	t.Run("Reload not supported", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		var reloadErr error
+		err := service.StartScheduled(nil, func(ctx context.Context) (time.Duration, error) {
+			reloadErr = service.Reload()
+			return 0, ggservice.ErrStopService
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if !errors.Is(reloadErr, ggservice.ErrNotSupported) {
+			t.Errorf("expected not supported error, got %v", reloadErr)
+		}
+	})
+}
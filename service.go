@@ -1,10 +1,14 @@
 package ggservice
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -12,30 +16,65 @@ import (
 // IService defines the interface for managing a service with start, stop, and force shutdown capabilities.
 type IService interface {
 	Start(startFunc func() error, runFunc func() error, stopFunc func() error, forceShutdownFunc func() error) error
+	StartScheduled(startFunc func() error, runFunc func(ctx context.Context) (time.Duration, error), stopFunc func() error, forceShutdownFunc func() error) error
 	Restart() error
 	Stop() error
 	ForceShutdown() error
 	GetIsRunning() bool
+	GetName() string
+	GetState() State
 	GetGracefulShutdownTime() time.Duration
-	SetGracefulShutdownTime(gracefulShutdownTime time.Duration)
+	SetGracefulShutdownTime(gracefulShutdownTime time.Duration) error
 	GetRunSleepDuration() time.Duration
-	SetRunSleepDuration(runSleepDuration time.Duration)
+	SetRunSleepDuration(runSleepDuration time.Duration) error
+	GetRunDelayBounds() (minDelay time.Duration, maxDelay time.Duration)
+	SetRunDelayBounds(minDelay time.Duration, maxDelay time.Duration) error
 	GetLogLevel() int
-	SetLogLevel(logLevel int)
+	SetLogLevel(logLevel int) error
+	GetPIDFile() string
+	SetPIDFile(pidFile string) error
 }
 
 // Service represents a service that can be started, stopped, and forcefully shutdown with graceful handling.
 type Service struct {
-	Name                            string        // Name of the service
-	gracefulShutdownTime            time.Duration // Timeout duration for graceful shutdown
-	isRunning                       bool          // Flag indicating whether the service is running
-	canRestart                      bool
-	runSleepDuration                time.Duration
-	logLevel                        int
-	isInitialized                   bool
-	isListenForInterruptInitialized bool
-	isInterrupted                   bool
-	customFunctions                 [4]func() error
+	Name                            string       // Name of the service
+	gracefulShutdownTime            atomic.Int64 // Timeout duration for graceful shutdown
+	isRunning                       atomic.Bool  // Flag indicating whether the service is running
+	canRestart                      atomic.Bool
+	runSleepDuration                atomic.Int64
+	logLevel                        atomic.Int32
+	isInitialized                   atomic.Bool
+	isListenForInterruptInitialized atomic.Bool
+	isInterrupted                   atomic.Bool
+	customFunctions                 [3]func() error                                  // Start, stop and forceShutdown functions
+	customRunFunc                   func(ctx context.Context) (time.Duration, error) // Run function, returning the delay until its next run
+	minRunDelay                     time.Duration
+	maxRunDelay                     time.Duration   // 0 for no maximum
+	ctx                             context.Context // Context of the started service, cancelled by Stop
+	cancel                          context.CancelFunc
+	shutdownHooks                   []shutdownHook  // Closers and shutdown functions, run in reverse order after the stop function
+	shutdownHookTimeout             atomic.Int64    // Timeout of every shutdown hook, 0 for the graceful shutdown time
+	goroutines                      *sync.WaitGroup // Goroutines started with Go since the service was started
+	goErrors                        []error         // Errors returned by goroutines started with Go
+	stopOnGoError                   atomic.Bool     // Stop the service when a goroutine started with Go fails
+	mutex                           sync.Mutex      // Guards ctx, cancel, the custom functions, goroutines, goErrors, shutdownHooks, the shutdown reports, traceRecorders, runnable, the run delay bounds and exitCodes
+	state                           atomic.Int32
+	restarts                        atomic.Uint64      // Amount of times Restart has started the service again
+	runFuncStartedAt                atomic.Int64       // Unix nanoseconds at which the current runFunc call started, 0 outside runFunc
+	pidFile                         string             // Path of the PID file, empty for no PID file
+	pidFileHandle                   *os.File           // Locked PID file while the service is started
+	hooks                           Hooks              // Lifecycle hooks
+	phaseStartedAt                  atomic.Int64       // Unix nanoseconds at which the current lifecycle phase started
+	stopReason                      atomic.Int32       // Why the service stopped, reset when the service starts
+	exitCodes                       map[StopReason]int // Exit codes of stop reasons set with SetExitCode
+	stopRequestedAt                 atomic.Int64       // Unix nanoseconds at which the stop was requested, 0 if it was not requested
+	forced                          atomic.Bool        // Whether a forced shutdown was triggered since the service started
+	shutdownInProgress              *ShutdownReport    // Report of the shutdown in progress
+	shutdownReport                  *ShutdownReport    // Report of the last shutdown
+	logShutdownReport               atomic.Bool        // Log the shutdown report as JSON
+	traceRecorders                  []*TraceRecorder   // Trace recorders recording the service
+	clock                           Clock              // Clock of the timers, nil for the clock of the time package
+	runnable                        Runnable           // Runnable the service was started with by StartRunnable, nil for functions
 }
 
 // Log levels
@@ -47,150 +86,455 @@ const (
 	LOG_LEVEL_ALL          // 4: Log all (info, warnings, and errors)
 )
 
-// New creates a new instance of Service with the given name and graceful shutdown timeout.
+// State represents the lifecycle state of a service.
+type State int
+
+// Service states
+const (
+	STATE_STOPPED  State = iota // 0: Not started, or stopped
+	STATE_STARTING              // 1: Running the start function
+	STATE_RUNNING               // 2: Start function completed, run loop is active
+	STATE_STOPPING              // 3: Stop was called, waiting for run loop and stop function
+)
+
+// String returns the lowercase name of the state.
+func (state State) String() string {
+	switch state {
+	case STATE_STOPPED:
+		return "stopped"
+	case STATE_STARTING:
+		return "starting"
+	case STATE_RUNNING:
+		return "running"
+	case STATE_STOPPING:
+		return "stopping"
+	}
+	return "unknown"
+}
+
+// New creates a new instance of Service with the name of the given service, its other fields are not used.
+//
+// Deprecated: use NewService, which validates the name and the configuration.
 func New(service *Service) IService {
-	return &Service{
-		Name:                 service.Name,
-		gracefulShutdownTime: 5 * time.Second,
-		logLevel:             LOG_LEVEL_ALL,
-		isRunning:            true,
-		canRestart:           true, // can only be changed by the program
+	return newService(service.Name)
+}
+
+// NewService creates a new instance of Service with the given name, configured by the options (like WithGracefulShutdownTime and WithLogLevel).
+// It returns an error if the name is empty or an option has an invalid value.
+func NewService(name string, options ...Option) (*Service, error) {
+	if name == "" {
+		return nil, errors.New("the name of a service can not be empty")
+	}
+	service := newService(name)
+	for _, option := range options {
+		err := option(service)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
 	}
+	return service, nil
 }
 
-// NewService creates a new instance of Service with the given name and graceful shutdown timeout.
-func NewService(name string) IService {
-	return New(&Service{Name: name})
+// newService creates a new instance of Service with the default configuration.
+func newService(name string) *Service {
+	service := &Service{Name: name}
+	service.gracefulShutdownTime.Store(int64(5 * time.Second))
+	service.logLevel.Store(LOG_LEVEL_ALL)
+	service.canRestart.Store(true) // can only be changed by the program
+	return service
 }
 
 func (s *Service) GetGracefulShutdownTime() time.Duration {
-	return s.gracefulShutdownTime
+	return time.Duration(s.gracefulShutdownTime.Load())
 }
 
-func (s *Service) SetGracefulShutdownTime(gracefulShutdownTime time.Duration) {
-	s.gracefulShutdownTime = gracefulShutdownTime
+// SetGracefulShutdownTime sets for how long the service may shut down before it is forced to. (default: 5 seconds)
+func (s *Service) SetGracefulShutdownTime(gracefulShutdownTime time.Duration) error {
+	if gracefulShutdownTime < 0 {
+		return fmt.Errorf("negative graceful shutdown time: %v", gracefulShutdownTime)
+	}
+	s.gracefulShutdownTime.Store(int64(gracefulShutdownTime))
+	return nil
 }
 
-func (s *Service) SetRunSleepDuration(runSleepDuration time.Duration) {
-	s.runSleepDuration = runSleepDuration
+// SetRunSleepDuration sets for how long the run loop of Start sleeps between runs. (default: 0)
+func (s *Service) SetRunSleepDuration(runSleepDuration time.Duration) error {
+	if runSleepDuration < 0 {
+		return fmt.Errorf("negative run sleep duration: %v", runSleepDuration)
+	}
+	s.runSleepDuration.Store(int64(runSleepDuration))
+	return nil
 }
 
 func (s *Service) GetRunSleepDuration() time.Duration {
-	return s.runSleepDuration
+	return time.Duration(s.runSleepDuration.Load())
+}
+
+func (s *Service) GetRunDelayBounds() (time.Duration, time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.minRunDelay, s.maxRunDelay
+}
+
+// SetRunDelayBounds bounds the delay between runs, both the delay returned by the run function of StartScheduled and the run sleep duration.
+// A maxDelay of 0 means there is no maximum. (note: ErrSkipSleep and Backoff are not bounded)
+func (s *Service) SetRunDelayBounds(minDelay time.Duration, maxDelay time.Duration) error {
+	if minDelay < 0 || maxDelay < 0 {
+		return fmt.Errorf("negative run delay bounds: %v, %v", minDelay, maxDelay)
+	}
+	if maxDelay != 0 && maxDelay < minDelay {
+		return fmt.Errorf("maximum run delay %v is below the minimum run delay %v", maxDelay, minDelay)
+	}
+	s.mutex.Lock()
+	s.minRunDelay = minDelay
+	s.maxRunDelay = maxDelay
+	s.mutex.Unlock()
+	return nil
 }
 
 func (s *Service) GetLogLevel() int {
-	return s.logLevel
+	return int(s.logLevel.Load())
+}
+
+// SetLogLevel sets which messages the service logs, from LOG_LEVEL_NONE to LOG_LEVEL_ALL. (default: LOG_LEVEL_ALL)
+func (s *Service) SetLogLevel(logLevel int) error {
+	if logLevel < LOG_LEVEL_NONE || logLevel > LOG_LEVEL_ALL {
+		return fmt.Errorf("unknown log level: %d", logLevel)
+	}
+	s.logLevel.Store(int32(logLevel))
+	return nil
+}
+
+func (s *Service) GetPIDFile() string {
+	return s.pidFile
+}
+
+// SetPIDFile makes Start write the process id to the given file and lock it, so only one instance of the program can run the service.
+// Start fails with an *AlreadyRunningError if another live instance holds the lock. The file is removed when the service stops.
+// It can not be changed while the service is started.
+// (note: the PID file can not be combined with an Upgrader, as the new process can not take the lock while the old process runs)
+func (s *Service) SetPIDFile(pidFile string) error {
+	err := s.errIfStarted("the PID file")
+	if err != nil {
+		return err
+	}
+	s.pidFile = pidFile
+	return nil
 }
 
-func (s *Service) SetLogLevel(logLevel int) {
-	s.logLevel = logLevel
+// errIfStarted returns an error if the service is started, for settings that can not be changed while the service is started.
+func (s *Service) errIfStarted(setting string) error {
+	if s.isInitialized.Load() {
+		return fmt.Errorf("%w: %s can not be changed while %s is started", ErrAlreadyStarted, setting, s.Name)
+	}
+	return nil
 }
 
+// Hooks returns the lifecycle hooks of the service, used to register hooks like OnStarting and OnError.
+func (s *Service) Hooks() *Hooks {
+	return &s.hooks
+}
+
+// GetIsRunning returns whether the service is started and not stopping.
 func (s *Service) GetIsRunning() bool {
-	return s.isInitialized && s.isRunning && s.canRestart
+	return s.isInitialized.Load() && s.isRunning.Load()
+}
+
+func (s *Service) GetName() string {
+	return s.Name
+}
+
+// GetState returns the current lifecycle state of the service. It is safe to call from any goroutine.
+func (s *Service) GetState() State {
+	return State(s.state.Load())
+}
+
+func (s *Service) setState(state State) {
+	s.state.Store(int32(state))
+}
+
+// runFuncDuration returns for how long the current runFunc call has been running, or 0 if runFunc is not being called.
+func (s *Service) runFuncDuration() time.Duration {
+	startedAt := s.runFuncStartedAt.Load()
+	if startedAt == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, startedAt))
+}
+
+// joinErrors joins the errors like errors.Join, but returns a single non-nil error as it is.
+func joinErrors(err error, other error) error {
+	if err == nil {
+		return other
+	}
+	if other == nil {
+		return err
+	}
+	return errors.Join(err, other)
+}
+
+// boundRunDelay keeps the delay until the next run within the run delay bounds.
+func (s *Service) boundRunDelay(delay time.Duration) time.Duration {
+	minDelay, maxDelay := s.GetRunDelayBounds()
+	if delay < minDelay {
+		return minDelay
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// restartCount returns the amount of times Restart has started the service again.
+func (s *Service) restartCount() uint64 {
+	return s.restarts.Load()
 }
 
 // Start starts the service with custom start, run, and stop functions.
+// The run function can steer the run loop by returning ErrStopService, ErrRestartService, ErrSkipSleep or Backoff(duration).
 func (s *Service) Start(startFunc func() error, runFunc func() error, stopFunc func() error, forceShutdownFunc func() error) error {
-	if s.isInitialized {
-		if s.logLevel >= LOG_LEVEL_WARN {
+	var scheduledRunFunc func(ctx context.Context) (time.Duration, error)
+	if runFunc != nil {
+		scheduledRunFunc = func(ctx context.Context) (time.Duration, error) {
+			return s.GetRunSleepDuration(), runFunc()
+		}
+	}
+	return s.StartScheduled(startFunc, scheduledRunFunc, stopFunc, forceShutdownFunc)
+}
+
+// StartScheduled starts the service like Start, but the run function returns the delay until its next run instead of using the run sleep duration.
+// The delay is kept within the bounds set by SetRunDelayBounds, and the context passed to the run function is cancelled by Stop.
+// The run loop wakes up right away when the service is stopped during the delay.
+func (s *Service) StartScheduled(startFunc func() error, runFunc func(ctx context.Context) (time.Duration, error), stopFunc func() error, forceShutdownFunc func() error) error {
+	return s.startScheduled(nil, startFunc, runFunc, stopFunc, forceShutdownFunc)
+}
+
+// startScheduled starts the service until it stops without a restart requested by the run function, the runnable is set when started with StartRunnable.
+func (s *Service) startScheduled(runnable Runnable, startFunc func() error, runFunc func(ctx context.Context) (time.Duration, error), stopFunc func() error, forceShutdownFunc func() error) error {
+	for {
+		isRestartRequested, err := s.start(runnable, startFunc, runFunc, stopFunc, forceShutdownFunc)
+		if err != nil || !isRestartRequested {
+			return err
+		}
+		s.restarts.Add(1)
+	}
+}
+
+// start starts the service once, and reports whether the run function requested a restart by returning ErrRestartService.
+func (s *Service) start(runnable Runnable, startFunc func() error, runFunc func(ctx context.Context) (time.Duration, error), stopFunc func() error, forceShutdownFunc func() error) (bool, error) {
+	if !s.isInitialized.CompareAndSwap(false, true) {
+		if s.GetLogLevel() >= LOG_LEVEL_WARN {
 			time.Sleep(20 * time.Millisecond) // to prevent log package from race condition logging most of the time
 			log.Println("Already started")
 		}
-		return nil
+		return false, fmt.Errorf("%w: %s", ErrAlreadyStarted, s.Name)
+	}
+	s.stopReason.Store(int32(STOP_REASON_NONE))
+	s.stopRequestedAt.Store(0)
+	s.forced.Store(false)
+
+	if s.pidFile != "" {
+		pidFileHandle, err := acquirePIDFile(s.pidFile)
+		if err != nil {
+			s.setStopReason(STOP_REASON_START_ERROR)
+			s.isInitialized.Store(false) // the service did not start, so it can be started again
+			return false, err
+		}
+		s.pidFileHandle = pidFileHandle
 	}
 
-	s.isInitialized = true
-	s.isRunning = true
-	s.canRestart = false
+	s.mutex.Lock()
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.goroutines = &sync.WaitGroup{}
+	s.runnable = runnable
 	s.customFunctions[0] = startFunc
-	s.customFunctions[1] = runFunc
-	s.customFunctions[2] = stopFunc
-	s.customFunctions[3] = forceShutdownFunc
+	s.customRunFunc = runFunc
+	s.customFunctions[1] = stopFunc
+	s.customFunctions[2] = forceShutdownFunc
+	s.mutex.Unlock()
+
+	s.isRunning.Store(true)
+	s.canRestart.Store(false)
+	s.setState(STATE_STARTING)
+	s.emit(PHASE_STARTING, nil)
 
-	if s.logLevel >= LOG_LEVEL_INFO {
+	if s.GetLogLevel() >= LOG_LEVEL_INFO {
 		time.Sleep(20 * time.Millisecond) // to prevent log package from race condition logging most of the time
 		log.Printf("Starting service: %s\n", s.Name)
 	}
 
 	// Custom start function if provided
+	var err error
 	if startFunc != nil {
-		err := startFunc()
+		startTime := time.Now()
+		s.traced(s.ctx, pprofPhaseStart, func(ctx context.Context) {
+			err = startFunc()
+		})
+		s.traceSpan(TRACE_SPAN_START, startTime, err)
 		if err != nil {
-			return err
+			s.setStopReason(STOP_REASON_START_ERROR)
+			s.emit(PHASE_ERROR, err)
 		}
 	} else {
 		// do nothing
 	}
 
+	if err == nil && s.isRunning.Load() {
+		s.setState(STATE_RUNNING)
+		s.emit(PHASE_RUNNING, nil)
+	}
+
 	// Custom run func if provided (in a loop as long as the service is running)
-	if runFunc != nil {
+	isRestartRequested := false
+	if err == nil && runFunc != nil {
 		// listen for interrupts for running service
-		if !s.isListenForInterruptInitialized {
-			s.isListenForInterruptInitialized = true
-			go s.listenForInterrupt(forceShutdownFunc) // Listen for interrupt signals
+		if s.isListenForInterruptInitialized.CompareAndSwap(false, true) {
+			s.goLabeled(pprofPhaseSignal, func() {
+				s.listenForInterrupt(forceShutdownFunc) // Listen for interrupt signals
+			})
 		}
 
-		for s.isRunning {
-			err := runFunc()
+		for s.isRunning.Load() {
+			runStartTime := time.Now()
+			s.runFuncStartedAt.Store(runStartTime.UnixNano())
+			var sleepDuration time.Duration
+			var runErr error
+			s.traced(s.ctx, pprofPhaseRun, func(ctx context.Context) {
+				sleepDuration, runErr = runFunc(ctx)
+			})
+			s.runFuncStartedAt.Store(0)
+			s.traceSpan(TRACE_SPAN_RUN, runStartTime, runErr)
+
+			sleepDuration = s.boundRunDelay(sleepDuration)
+			var backoff *BackoffError
+			switch {
+			case runErr == nil:
+			case errors.Is(runErr, ErrSkipSleep):
+				sleepDuration = 0
+			case errors.As(runErr, &backoff):
+				sleepDuration = backoff.Duration
+			case errors.Is(runErr, ErrStopService):
+				_ = s.stop(STOP_REASON_REQUESTED) // the service is running, so stop can not fail
+				continue
+			case errors.Is(runErr, ErrRestartService):
+				isRestartRequested = true
+				if s.GetLogLevel() >= LOG_LEVEL_INFO {
+					time.Sleep(20 * time.Millisecond) // to prevent log package from race condition logging most of the time
+					log.Println("Run function requested restart of service: " + s.Name)
+				}
+				s.emit(PHASE_RESTART, nil)
+				_ = s.stop(STOP_REASON_RESTART)
+				continue
+			default:
+				s.setStopReason(STOP_REASON_RUN_ERROR)
+				s.emit(PHASE_ERROR, runErr)
+				err = runErr
+			}
 			if err != nil {
-				return err
+				break
 			}
 
-			// if we define the runSleepDuration to be above every millisecond, then we are allowed to sleep
-			if s.GetRunSleepDuration() > 1*time.Millisecond {
-				time.Sleep(s.GetRunSleepDuration())
+			// if we define the runSleepDuration to be above every millisecond, then we are allowed to sleep (until the service is stopped)
+			if sleepDuration > 1*time.Millisecond {
+				timer := s.GetClock().NewTimer(sleepDuration)
+				select {
+				case <-timer.C():
+				case <-s.ctx.Done():
+					timer.Stop()
+				}
 			}
 		}
 	} else {
 		// do nothing
 	}
 
-	// Custom stop func if provided
+	// from here on the service is stopping, also when the start or run function failed
+	s.setStopReason(STOP_REASON_DONE)
+	s.isRunning.Store(false)
+	s.mutex.Lock()
+	s.cancel()
+	s.mutex.Unlock()
+	report := s.newShutdownReport()
+
+	// goroutines started with Go are cancelled now, they finish before the stop function runs
+	phaseStartTime := time.Now()
+	err = joinErrors(err, s.waitGoroutines())
+	s.updateShutdownReport(func(report *ShutdownReport) {
+		report.GoroutinesDuration = time.Since(phaseStartTime)
+	})
+
+	// Custom stop func if provided (like a defer, it runs whenever the service got at least partway started)
 	if stopFunc != nil {
-		err := stopFunc()
-		if err != nil {
-			return err
+		phaseStartTime = time.Now()
+		var stopErr error
+		s.traced(context.Background(), pprofPhaseStop, func(ctx context.Context) {
+			stopErr = stopFunc()
+		})
+		s.traceSpan(TRACE_SPAN_STOP, phaseStartTime, stopErr)
+		s.updateShutdownReport(func(report *ShutdownReport) {
+			report.StopFuncDuration = time.Since(phaseStartTime)
+		})
+		if stopErr != nil {
+			s.emit(PHASE_ERROR, stopErr)
+			err = joinErrors(err, stopErr)
 		}
 	} else {
 		// do nothing
 	}
 
-	if s.logLevel >= LOG_LEVEL_INFO {
+	// Registered closers and shutdown functions
+	shutdownErr := s.runShutdownHooks()
+	if shutdownErr != nil {
+		s.emit(PHASE_ERROR, shutdownErr)
+		err = joinErrors(err, shutdownErr)
+	}
+
+	if err == nil && s.GetLogLevel() >= LOG_LEVEL_INFO {
 		time.Sleep(20 * time.Millisecond) // to prevent log package from race condition logging most of the time
 		log.Printf("%s stopped gracefully\n", s.Name)
 	}
 
-	s.canRestart = true
-	s.isInitialized = false
-	return nil
+	if err != nil {
+		err = &StopError{Service: s.Name, Reason: s.GetStopReason(), Err: err}
+	}
+	s.finishShutdownReport(report, err)
+	s.setState(STATE_STOPPED)
+	s.releasePIDFile()
+	s.emit(PHASE_STOPPED, err)
+	s.canRestart.Store(true)
+	s.isInitialized.Store(false)
+	return err == nil && isRestartRequested && !s.isInterrupted.Load(), err
 }
 
 // Restart restarts the service
 func (s *Service) Restart() error {
-	if !s.isInterrupted {
-		if s.logLevel >= LOG_LEVEL_INFO {
+	if !s.isInterrupted.Load() {
+		if s.GetLogLevel() >= LOG_LEVEL_INFO {
 			time.Sleep(20 * time.Millisecond) // to prevent log package from race condition logging most of the time
 			log.Println("Calling for restart of service: " + s.Name)
 		}
-		err := s.Stop() // ignore stop err
-		if err != nil {
+		s.emit(PHASE_RESTART, nil)
+		err := s.stop(STOP_REASON_RESTART) // ignore stop err, a stopped service is started again
+		if err != nil && s.GetLogLevel() >= LOG_LEVEL_WARN {
 			log.Println(err)
 		}
 
 		for {
-			if s.logLevel >= LOG_LEVEL_INFO {
+			if s.GetLogLevel() >= LOG_LEVEL_INFO {
 				time.Sleep(20 * time.Millisecond) // to prevent log package from race condition logging most of the time
 			}
-			if s.isInterrupted {
+			if s.isInterrupted.Load() {
 				break
 			}
 
-			if s.canRestart {
-				s.canRestart = false
-				s.isRunning = true
-				err := s.Start(s.customFunctions[0], s.customFunctions[1], s.customFunctions[2], s.customFunctions[3])
+			if s.canRestart.CompareAndSwap(true, false) {
+				s.restarts.Add(1)
+				s.mutex.Lock()
+				runnable := s.runnable
+				customFunctions := s.customFunctions
+				customRunFunc := s.customRunFunc
+				s.mutex.Unlock()
+				err := s.startScheduled(runnable, customFunctions[0], customRunFunc, customFunctions[1], customFunctions[2])
 				return err
 			}
 		}
@@ -200,32 +544,52 @@ func (s *Service) Restart() error {
 
 // Stop stops the service by setting isRunning to false.
 func (s *Service) Stop() error {
-	if s.isRunning {
-		if s.logLevel >= LOG_LEVEL_INFO {
+	return s.stop(STOP_REASON_STOP)
+}
+
+// stop stops the service and records why it stops.
+func (s *Service) stop(reason StopReason) error {
+	if s.isRunning.CompareAndSwap(true, false) {
+		s.setStopReason(reason)
+		s.stopRequested()
+		if s.GetLogLevel() >= LOG_LEVEL_INFO {
 			time.Sleep(20 * time.Millisecond) // to prevent log package from race condition logging most of the time
 			log.Println("Stopping service: " + s.Name)
 		}
-		s.isRunning = false
+		s.mutex.Lock()
+		if s.cancel != nil {
+			s.cancel()
+		}
+		s.mutex.Unlock()
+		if s.GetState() != STATE_STOPPED {
+			s.setState(STATE_STOPPING)
+			s.emit(PHASE_STOPPING, nil)
+		}
 		return nil
 	}
 
-	if s.logLevel >= LOG_LEVEL_WARN {
-		return errors.New("Service was not running: " + s.Name)
-	}
-	return nil
+	return fmt.Errorf("%w: %s", ErrNotRunning, s.Name)
 }
 
 // ForceShutdown forcefully stops both the service and the whole program and logs an error. (note: forcing shutdown is not graceful)
+// The program exits with the exit code of STOP_REASON_FORCE_SHUTDOWN (default: 1).
 func (s *Service) ForceShutdown() error {
 	err := s.Stop()
 	if err != nil {
 		return err
 	}
-	if s.logLevel >= LOG_LEVEL_ERROR {
+	s.forceExit(STOP_REASON_FORCE_SHUTDOWN)
+	return nil
+}
+
+// forceExit exits the program with the exit code of the reason.
+func (s *Service) forceExit(reason StopReason) {
+	s.stopReason.Store(int32(reason)) // a forced shutdown overrules why the service was stopping
+	s.forceShutdownReport()
+	if s.GetLogLevel() >= LOG_LEVEL_ERROR {
 		log.Println("(Timeout) forced shutdown of program with all its running services")
 	}
-	os.Exit(1)
-	return nil
+	exit(s.GetExitCode())
 }
 
 // listenForInterrupt listens for interrupt signals and triggers shutdown.
@@ -233,30 +597,68 @@ func (s *Service) listenForInterrupt(forceShutdown func() error) {
 	osSignal := make(chan os.Signal, 1)
 	signal.Notify(osSignal, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	<-osSignal // Block until a signal is received
-	s.isInterrupted = true
-	// printing interrupt signal warning regardless of s.PrintLog
-	if s.logLevel >= LOG_LEVEL_WARN {
-		log.Printf("%s received interrupt signal, initiating graceful shutdown (timeout: %v)\n", s.Name, s.GetGracefulShutdownTime())
-	}
 
 	signal.Stop(osSignal)
 	close(osSignal)
 
-	err := s.Stop() // Stop the service
+	s.interrupt(forceShutdown)
+}
+
+// Signal handles the signal as if the program received it, without signalling the process. (for example in tests)
+// An interrupt signal (os.Interrupt, SIGINT or SIGTERM) shuts down the service gracefully, other signals are ignored.
+func (s *Service) Signal(sig os.Signal) {
+	if sig != os.Interrupt && sig != syscall.SIGINT && sig != syscall.SIGTERM {
+		return
+	}
+	s.interrupt(s.getForceShutdownFunc())
+}
+
+// getForceShutdownFunc returns the force shutdown function the service was last started with.
+func (s *Service) getForceShutdownFunc() func() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.customFunctions[2]
+}
+
+// interrupt shuts down the service gracefully after an interrupt signal.
+func (s *Service) interrupt(forceShutdown func() error) {
+	if !s.isInterrupted.Load() {
+		_ = SdNotify("STOPPING=1") // does nothing when not running under systemd
+	}
+	s.shutdownGracefully(STOP_REASON_SIGNAL, "received interrupt signal", forceShutdown)
+}
+
+// shutdownGracefully stops the service for good (it can not be restarted) and schedules a forced shutdown if the graceful shutdown time elapses.
+func (s *Service) shutdownGracefully(reason StopReason, message string, forceShutdown func() error) {
+	if !s.isInterrupted.CompareAndSwap(false, true) {
+		return
+	}
+	// printing interrupt signal warning regardless of s.PrintLog
+	if s.GetLogLevel() >= LOG_LEVEL_WARN {
+		log.Printf("%s %s, initiating graceful shutdown (timeout: %v)\n", s.Name, message, s.GetGracefulShutdownTime())
+	}
+
+	err := s.stop(reason) // Stop the service
 	if err != nil {
 		log.Println(err)
 	}
 
 	// Schedule a forced shutdown if the graceful shutdown time elapses
-	go func() {
-		<-time.After(s.GetGracefulShutdownTime())
+	timer := s.GetClock().NewTimer(s.GetGracefulShutdownTime())
+	s.goLabeled(pprofPhaseForceShutdown, func() {
+		<-timer.C()
+		if s.GetState() == STATE_STOPPED {
+			return // the service stopped in time
+		}
 
 		// Custom forceShutdown func if provided
 		if forceShutdown != nil {
+			s.stopReason.Store(int32(STOP_REASON_FORCE_TIMEOUT))
+			s.forceShutdownReport()
 			_ = forceShutdown() // ignore err
 		} else {
-			// if forceShutdownFunc is not implemented by the user, then run ForceShutdown (exits program with log)
-			_ = s.ForceShutdown() // ignore err
+			// if forceShutdownFunc is not implemented by the user, then exit the program with log
+			s.forceExit(STOP_REASON_FORCE_TIMEOUT)
 		}
-	}()
+	})
 }
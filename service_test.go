@@ -1,9 +1,11 @@
 package ggservice_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
 	"log"
 	"os"
 	"sync"
@@ -20,13 +22,11 @@ var startFunc = func() error {
 
 var runFunc = func() error {
 	fmt.Println("running service...")
-	time.Sleep(1 * time.Second)
 	return nil
 }
 
 var stopFunc = func() error {
 	fmt.Println("stopped service...")
-	time.Sleep(1 * time.Second)
 	return nil
 }
 
@@ -37,23 +37,132 @@ func TestNew(test *testing.T) {
 	}
 }
 
-func TestNewService(t *testing.T) {
-	service := ggservice.NewService("My Service")
-	if service == nil {
-		t.Error("Could not create new service")
+// newService creates a new service for a test, and fails the test if it can not be created.
+func newService(t testing.TB, name string, options ...ggservice.Option) *ggservice.Service {
+	t.Helper()
+	service, err := ggservice.NewService(name, options...)
+	if err != nil {
+		t.Fatal(err)
 	}
+	return service
+}
+
+func TestNewService(t *testing.T) {
+	t.Run("Options", func(t *testing.T) {
+		service, err := ggservice.NewService("My Service",
+			ggservice.WithGracefulShutdownTime(time.Minute),
+			ggservice.WithRunSleepDuration(time.Second),
+			ggservice.WithRunDelayBounds(time.Millisecond, time.Hour),
+			ggservice.WithLogLevel(ggservice.LOG_LEVEL_WARN),
+			ggservice.WithShutdownHookTimeout(10*time.Second),
+			ggservice.WithStopOnGoError(true),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		minDelay, maxDelay := service.GetRunDelayBounds()
+		if service.GetGracefulShutdownTime() != time.Minute || service.GetRunSleepDuration() != time.Second || minDelay != time.Millisecond || maxDelay != time.Hour ||
+			service.GetLogLevel() != ggservice.LOG_LEVEL_WARN || service.GetShutdownHookTimeout() != 10*time.Second || !service.GetStopOnGoError() {
+			t.Error("expected the options to configure the service")
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			options []ggservice.Option
+		}{
+			{"", nil},
+			{"Negative graceful shutdown time", []ggservice.Option{ggservice.WithGracefulShutdownTime(-time.Second)}},
+			{"Negative run sleep duration", []ggservice.Option{ggservice.WithRunSleepDuration(-time.Second)}},
+			{"Maximum below minimum run delay", []ggservice.Option{ggservice.WithRunDelayBounds(time.Hour, time.Second)}},
+			{"Unknown log level", []ggservice.Option{ggservice.WithLogLevel(ggservice.LOG_LEVEL_ALL + 1)}},
+			{"Negative shutdown hook timeout", []ggservice.Option{ggservice.WithShutdownHookTimeout(-time.Second)}},
+		}
+		for _, test := range tests {
+			service, err := ggservice.NewService(test.name, test.options...)
+			if err == nil || service != nil {
+				t.Errorf("%q: expected error", test.name)
+			}
+		}
+	})
+
+	t.Run("Unsafe after start", func(t *testing.T) {
+		service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE))
+		err := service.StartScheduled(nil, func(ctx context.Context) (time.Duration, error) {
+			if !errors.Is(service.SetPIDFile("my-service.pid"), ggservice.ErrAlreadyStarted) {
+				t.Error("expected the PID file to be rejected while the service is started")
+			}
+			if service.SetLogLevel(ggservice.LOG_LEVEL_ERROR) != nil {
+				t.Error("expected the log level to be changeable while the service is started")
+			}
+			return 0, ggservice.ErrStopService
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if service.GetPIDFile() != "" {
+			t.Errorf("expected no PID file, got %q", service.GetPIDFile())
+		}
+	})
+
+	t.Run("Safe while running", func(t *testing.T) {
+		service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE))
+		service.OnShutdown(func(ctx context.Context) error {
+			return nil
+		})
+		runs := 0
+		changed := make(chan struct{})
+		err := service.Start(nil, func() error {
+			runs++
+			if runs == 1 {
+				// the settings are changed while the run loop, the hooks and the shutdown read them
+				go func() {
+					defer close(changed)
+					for i := 0; i < 100; i++ {
+						errs := []error{
+							service.SetLogLevel(ggservice.LOG_LEVEL_NONE),
+							service.SetRunSleepDuration(time.Duration(i%2) * time.Millisecond),
+							service.SetGracefulShutdownTime(5*time.Second + time.Duration(i)),
+							service.SetRunDelayBounds(0, time.Second),
+							service.SetShutdownHookTimeout(time.Second),
+						}
+						service.SetStopOnGoError(i%2 == 0)
+						service.SetLogShutdownReport(false)
+						service.SetExitCode(ggservice.STOP_REASON_STOP, i)
+						for _, err := range errs {
+							if err != nil {
+								t.Error(err)
+							}
+						}
+					}
+				}()
+			}
+			if runs == 50 {
+				<-changed
+				return ggservice.ErrStopService
+			}
+			return nil
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if service.GetLogLevel() != ggservice.LOG_LEVEL_NONE || service.GetExitCode() != 0 {
+			t.Error("expected the settings changed while running to be kept")
+		}
+	})
 }
 
 func TestService_StartStop(t *testing.T) {
 	t.Run("Without custom functions", func(t *testing.T) {
-		service := ggservice.NewService("My Service")
+		service := newService(t, "My Service")
 		err := service.Start(nil, nil, nil, nil)
 		if err != nil {
 			t.Error(err)
 		}
 	})
 	t.Run("With start function", func(t *testing.T) {
-		service := ggservice.NewService("My Service")
+		service := newService(t, "My Service")
 
 		err := service.Start(startFunc, nil, nil, nil)
 		if err != nil {
@@ -61,95 +170,158 @@ func TestService_StartStop(t *testing.T) {
 		}
 	})
 	t.Run("With stop function", func(t *testing.T) {
-		service := ggservice.NewService("My Service")
+		service := newService(t, "My Service")
 
 		err := service.Start(nil, nil, stopFunc, nil)
 		if err != nil {
 			t.Error(err)
 		}
 	})
-	// waitgroup dependent below:
+	// the run loop sleeps on a fake clock below:
 	t.Run("With custom functions", func(t *testing.T) {
-		waitgroup := sync.WaitGroup{}
-		waitgroup.Add(2)
-		var service ggservice.IService
+		clock := ggservicetest.NewFakeClock(time.Now())
+		service := newService(t, "My Service", ggservice.WithClock(clock), ggservice.WithRunSleepDuration(time.Second))
+
+		runs := make(chan struct{}, 10)
+		done := ggservicetest.StartAsync(service, nil, func() error {
+			runs <- struct{}{}
+			return runFunc()
+		}, nil, nil)
+		<-runs
+		clock.AwaitTimers(t, 1, time.Second) // the run loop sleeps the run sleep duration
+		clock.Advance(time.Second)
+		<-runs
 
-		go func() {
-			defer waitgroup.Done()
-			service = ggservice.NewService("My Service")
-			err := service.Start(nil, runFunc, nil, nil)
-			if err != nil {
-				t.Error(err)
-			}
-		}()
-		go func() {
-			defer waitgroup.Done()
-			time.Sleep(3 * time.Second)
-			err := service.Stop()
-			if err != nil {
-				t.Error(err)
-			}
-		}()
-		waitgroup.Wait()
+		err := service.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = <-done
+		if err != nil {
+			t.Error(err)
+		}
 	})
 	t.Run("With full custom functions", func(t *testing.T) {
-		waitgroup := sync.WaitGroup{}
-		waitgroup.Add(2)
-		var service2 ggservice.IService
+		clock := ggservicetest.NewFakeClock(time.Now())
+		service2 := newService(t, "My Service", ggservice.WithClock(clock), ggservice.WithRunSleepDuration(time.Second))
 
 		forceShutdownFunc := func() error {
 			fmt.Println("stopped service...")
-			time.Sleep(1 * time.Second)
 			return nil
 		}
 
-		go func() {
-			defer waitgroup.Done()
-			service2 = ggservice.NewService("My Service")
-			err := service2.Start(startFunc, runFunc, stopFunc, forceShutdownFunc)
-			if err != nil {
-				t.Error(err)
-			}
-		}()
+		runs := make(chan struct{}, 10)
+		done := ggservicetest.StartAsync(service2, startFunc, func() error {
+			runs <- struct{}{}
+			return runFunc()
+		}, stopFunc, forceShutdownFunc)
+		<-runs
+		clock.AwaitTimers(t, 1, time.Second)
+		clock.Advance(time.Second)
+		<-runs
+
+		err := service2.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = <-done
+		if err != nil {
+			t.Error(err)
+		}
+		ggservicetest.AssertStopped(t, service2)
+	})
+}
 
-		go func() {
-			defer waitgroup.Done()
-			time.Sleep(3 * time.Second)
-			err := service2.Stop()
-			if err != nil {
-				t.Error(err)
-			}
-		}()
-		waitgroup.Wait()
+func TestService_StartErrors(t *testing.T) {
+	startErr := errors.New("start failed")
+	runErr := errors.New("run failed")
+	stopErr := errors.New("stop failed")
+
+	t.Run("Start function error", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		isStopped := false
+		err := service.Start(func() error {
+			return startErr
+		}, runFunc, func() error {
+			isStopped = true
+			return nil
+		}, nil)
+		if !errors.Is(err, startErr) {
+			t.Errorf("expected start error, got %v", err)
+		}
+		if !isStopped {
+			t.Error("expected stop function to run")
+		}
+		if service.GetState() != ggservice.STATE_STOPPED {
+			t.Errorf("expected service to be stopped, got %s", service.GetState())
+		}
+
+		// the service can be started again after a failed start
+		err = service.Start(nil, nil, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Run and stop function errors", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		err := service.Start(nil, func() error {
+			return runErr
+		}, func() error {
+			return stopErr
+		}, nil)
+		if !errors.Is(err, runErr) || !errors.Is(err, stopErr) {
+			t.Errorf("expected run and stop errors, got %v", err)
+		}
+		if service.GetState() != ggservice.STATE_STOPPED {
+			t.Errorf("expected service to be stopped, got %s", service.GetState())
+		}
 	})
 }
 
 func TestService_Restart(t *testing.T) {
-	service := ggservice.NewService("My Service")
+	clock := ggservicetest.NewFakeClock(time.Now())
+	service := newService(t, "My Service", ggservice.WithClock(clock), ggservice.WithRunSleepDuration(time.Second))
 
 	testFunc := func() {
-		waitgroup := sync.WaitGroup{}
-
-		go func() {
-			waitgroup.Add(1)
-			defer waitgroup.Done()
-			time.Sleep(3 * time.Second)
-			err := service.Restart() // this is a blocking call
-			if err != nil {
-				t.Error(err)
-			}
-		}()
+		starts := make(chan struct{}, 10)
+		runs := make(chan struct{}, 10)
+		done := ggservicetest.StartAsync(service, func() error {
+			starts <- struct{}{}
+			return nil
+		}, func() error {
+			runs <- struct{}{}
+			return runFunc()
+		}, nil, nil)
+		<-starts
+		<-runs
+		clock.AwaitTimers(t, 1, time.Second)
+
+		restarted := make(chan error, 1)
 		go func() {
-			waitgroup.Add(1)
-			defer waitgroup.Done()
-			time.Sleep(6 * time.Second)
-			err := service.Stop()
-			if err != nil {
-				t.Error(err)
-			}
+			restarted <- service.Restart() // this is a blocking call
 		}()
-		err := service.Start(nil, runFunc, nil, nil) // this is a blocking call
-		waitgroup.Wait()
+		err := <-done // Start returns once the service is stopped for the restart
+		if err != nil {
+			t.Error(err)
+		}
+
+		// the restarted service runs on the clock again
+		<-starts
+		<-runs
+		clock.AwaitTimers(t, 1, time.Second)
+		clock.Advance(time.Second)
+		<-runs
+
+		err = service.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = <-restarted
 		if err != nil {
 			t.Error(err)
 		}
@@ -181,18 +353,155 @@ func TestService_Restart(t *testing.T) {
 
 }
 
+func TestService_StartScheduled(t *testing.T) {
+	t.Run("Wakes up on stop", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		var runCtx context.Context
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			err := service.Stop()
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+		startTime := time.Now()
+		err := service.StartScheduled(nil, func(ctx context.Context) (time.Duration, error) {
+			runCtx = ctx
+			return time.Hour, nil
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if elapsed := time.Since(startTime); elapsed > 10*time.Second {
+			t.Errorf("expected stop to end the delay, took %v", elapsed)
+		}
+		if runCtx == nil || runCtx.Err() == nil {
+			t.Error("expected the run context to be cancelled")
+		}
+	})
+
+	t.Run("With delay bounds", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		service.SetRunDelayBounds(2*time.Millisecond, 5*time.Millisecond)
+
+		runs := 0
+		err := service.StartScheduled(nil, func(ctx context.Context) (time.Duration, error) {
+			runs++
+			if runs == 3 {
+				return 0, ggservice.ErrStopService
+			}
+			return time.Hour, nil // bounded to 5 milliseconds
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if runs != 3 {
+			t.Errorf("expected 3 runs, got %d", runs)
+		}
+	})
+}
+
 func TestService_ForceShutdown(t *testing.T) {
-	t.Errorf("test not implemented yet")
+	exitCode := -1
+	restore := ggservice.SetExit(func(code int) {
+		exitCode = code
+	})
+	defer restore()
+
+	t.Run("Not running", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		err := service.ForceShutdown()
+		if !errors.Is(err, ggservice.ErrNotRunning) {
+			t.Errorf("expected not running error, got %v", err)
+		}
+		if exitCode != -1 {
+			t.Errorf("expected no exit, got exit code %d", exitCode)
+		}
+	})
+
+	t.Run("Running", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		service.SetExitCode(ggservice.STOP_REASON_FORCE_SHUTDOWN, 3)
+		err := service.Start(nil, func() error {
+			return service.ForceShutdown()
+		}, nil, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if exitCode != 3 {
+			t.Errorf("expected exit code 3, got %d", exitCode)
+		}
+		if service.GetStopReason() != ggservice.STOP_REASON_FORCE_SHUTDOWN {
+			t.Errorf("expected stop reason %s, got %s", ggservice.STOP_REASON_FORCE_SHUTDOWN, service.GetStopReason())
+		}
+	})
 }
 
 func TestService_listenForInterrupt(t *testing.T) {
-	t.Errorf("test not implemented yet")
+	clock := ggservicetest.NewFakeClock(time.Now())
+	service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE), ggservice.WithClock(clock), ggservice.WithRunSleepDuration(time.Hour))
+
+	isStopped := false
+	done := ggservicetest.StartAsync(service, nil, func() error {
+		return nil
+	}, func() error {
+		isStopped = true
+		return nil
+	}, nil)
+
+	ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, time.Second)
+	clock.AwaitTimers(t, 1, time.Second)
+	ggservicetest.Interrupt(service) // interrupts the run sleep duration
+	err := <-done
+	if err != nil {
+		t.Error(err)
+	}
+	ggservicetest.AssertStopped(t, service)
+	if !isStopped {
+		t.Error("expected stop function to run")
+	}
+	if service.GetStopReason() != ggservice.STOP_REASON_SIGNAL {
+		t.Errorf("expected stop reason %s, got %s", ggservice.STOP_REASON_SIGNAL, service.GetStopReason())
+	}
+}
+
+func TestService_Signal_duringStart(t *testing.T) {
+	clock := ggservicetest.NewFakeClock(time.Now())
+	service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE), ggservice.WithClock(clock), ggservice.WithRunSleepDuration(time.Hour))
+
+	done := ggservicetest.StartAsync(service, nil, func() error {
+		return nil
+	}, nil, func() error {
+		return nil
+	})
+	ggservicetest.Interrupt(service) // reads the force shutdown function while Start stores it
+
+	// a signal before the service was running does not stop it, so it is stopped until Start returns
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Error(err)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+			_ = service.Stop()
+		}
+	}
 }
 
 // EXAMPLES:
 
 func ExampleNewService() {
-	service := ggservice.NewService("My Service")
+	service, err := ggservice.NewService("My Service", ggservice.WithGracefulShutdownTime(5*time.Second))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	ExampleStart := func() error {
 		fmt.Println("this runs when service starts")
@@ -214,7 +523,7 @@ func ExampleNewService() {
 		return nil
 	}
 
-	err := service.Start(ExampleStart, ExampleRun, ExampeStop, ExampeForceShutdown) // this is a blocking call
+	err = service.Start(ExampleStart, ExampleRun, ExampeStop, ExampeForceShutdown) // this is a blocking call
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -274,9 +583,13 @@ func ExampleService_Restart() {
 	}
 
 	// creating new service with name and graceful shutdown time duration
-	service := ggservice.NewService("SSG Service")
-	service.SetGracefulShutdownTime(5 * time.Second)
-	service.SetLogLevel(ggservice.LOG_LEVEL_ALL)
+	service, err := ggservice.NewService("SSG Service",
+		ggservice.WithGracefulShutdownTime(5*time.Second),
+		ggservice.WithLogLevel(ggservice.LOG_LEVEL_ALL),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// starting the service (please note you can choose to not implement any of these by using nil instead)
 	waitgroup := &sync.WaitGroup{}
@@ -0,0 +1,111 @@
+package ggservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime/pprof"
+	"time"
+)
+
+// shutdownHook is a closer or shutdown function run after the stop function.
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// RegisterCloser registers a resource (like a database or a file) to be closed after the stop function when the service stops.
+// Closers and shutdown functions run in reverse order of registration, so resources opened later are closed first.
+// They only run once, resources opened by the start function are registered again on restart.
+func (s *Service) RegisterCloser(name string, closer io.Closer) {
+	s.addShutdownHook(name, func(ctx context.Context) error {
+		return closer.Close()
+	})
+}
+
+// OnShutdown registers a function to be called after the stop function when the service stops (see RegisterCloser).
+// The context is cancelled when the shutdown hook timeout elapses.
+func (s *Service) OnShutdown(shutdownFunc func(ctx context.Context) error) {
+	s.addShutdownHook("shutdown function", shutdownFunc)
+}
+
+func (s *Service) GetShutdownHookTimeout() time.Duration {
+	shutdownHookTimeout := time.Duration(s.shutdownHookTimeout.Load())
+	if shutdownHookTimeout <= 0 {
+		return s.GetGracefulShutdownTime()
+	}
+	return shutdownHookTimeout
+}
+
+// SetShutdownHookTimeout sets for how long every closer and shutdown function may run. (default: the graceful shutdown time)
+func (s *Service) SetShutdownHookTimeout(shutdownHookTimeout time.Duration) error {
+	if shutdownHookTimeout < 0 {
+		return fmt.Errorf("negative shutdown hook timeout: %v", shutdownHookTimeout)
+	}
+	s.shutdownHookTimeout.Store(int64(shutdownHookTimeout))
+	return nil
+}
+
+func (s *Service) addShutdownHook(name string, fn func(ctx context.Context) error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, shutdownHook{name: name, fn: fn})
+}
+
+// runShutdownHooks runs and removes the registered closers and shutdown functions in reverse order, and returns their joined errors.
+func (s *Service) runShutdownHooks() error {
+	s.mutex.Lock()
+	hooks := s.shutdownHooks
+	s.shutdownHooks = nil
+	s.mutex.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		startTime := time.Now()
+		err := s.runShutdownHook(hooks[i])
+		closerReport := CloserReport{Name: hooks[i].name, Duration: time.Since(startTime), Err: err}
+		s.updateShutdownReport(func(report *ShutdownReport) {
+			report.Closers = append(report.Closers, closerReport)
+		})
+		if err != nil {
+			if s.GetLogLevel() >= LOG_LEVEL_WARN {
+				log.Printf("%s: could not close %s: %v\n", s.Name, hooks[i].name, err)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", hooks[i].name, err))
+			continue
+		}
+		if s.GetLogLevel() >= LOG_LEVEL_ALL {
+			log.Printf("%s: closed %s (%v)\n", s.Name, hooks[i].name, time.Since(startTime))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runShutdownHook runs a single closer or shutdown function, without waiting for it longer than the shutdown hook timeout.
+func (s *Service) runShutdownHook(hook shutdownHook) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timer := s.GetClock().NewTimer(s.GetShutdownHookTimeout())
+	defer timer.Stop()
+
+	done := make(chan error, 1)
+	go pprof.Do(ctx, s.pprofLabels(pprofPhaseShutdown), func(ctx context.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered != nil {
+				done <- fmt.Errorf("panic: %v", recovered)
+			}
+		}()
+		done <- hook.fn(ctx)
+	})
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C():
+		cancel()
+		return fmt.Errorf("timed out after %v", s.GetShutdownHookTimeout())
+	}
+}
@@ -0,0 +1,82 @@
+package ggservice_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+// closer records the order resources are closed in.
+type closer struct {
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (c closer) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+func TestService_RegisterCloser(t *testing.T) {
+	t.Run("Reverse order", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		var closed []string
+		err := service.Start(func() error {
+			service.RegisterCloser("database", closer{name: "database", closed: &closed})
+			service.OnShutdown(func(ctx context.Context) error {
+				closed = append(closed, "flush")
+				return nil
+			})
+			service.RegisterCloser("producer", closer{name: "producer", closed: &closed})
+			return nil
+		}, nil, func() error {
+			closed = append(closed, "stop")
+			return nil
+		}, nil)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := []string{"stop", "producer", "flush", "database"}
+		if !reflect.DeepEqual(closed, expected) {
+			t.Errorf("expected %v, got %v", expected, closed)
+		}
+	})
+
+	t.Run("Errors and timeout", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		service.SetShutdownHookTimeout(10 * time.Millisecond)
+
+		closeErr := errors.New("close failed")
+		var closed []string
+		service.RegisterCloser("database", closer{name: "database", closed: &closed, err: closeErr})
+		service.OnShutdown(func(ctx context.Context) error {
+			time.Sleep(time.Second) // ignores the context, but is not waited for
+			return nil
+		})
+
+		startTime := time.Now()
+		err := service.Start(nil, nil, nil, nil)
+		if !errors.Is(err, closeErr) {
+			t.Errorf("expected close error, got %v", err)
+		}
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("expected timeout error, got %v", err)
+		}
+		if elapsed := time.Since(startTime); elapsed >= time.Second {
+			t.Errorf("expected shutdown function to time out, took %v", elapsed)
+		}
+		if len(closed) != 1 {
+			t.Errorf("expected database to be closed, got %v", closed)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package ggservice
+
+import "os"
+
+// exit ends the program, it is replaced in tests.
+var exit = os.Exit
+
+// StopReason tells why a service stopped.
+type StopReason int
+
+// Stop reasons
+const (
+	STOP_REASON_NONE           StopReason = iota // 0: The service has not stopped (yet)
+	STOP_REASON_DONE                             // 1: The service stopped by itself, as it has no run function
+	STOP_REASON_STOP                             // 2: Stop was called
+	STOP_REASON_REQUESTED                        // 3: The run function returned ErrStopService
+	STOP_REASON_SIGNAL                           // 4: An interrupt signal was received
+	STOP_REASON_RESTART                          // 5: The service is restarted (Restart or ErrRestartService)
+	STOP_REASON_UPGRADE                          // 6: The program is upgraded to a new process (see Upgrader)
+	STOP_REASON_START_ERROR                      // 7: The start function returned an error
+	STOP_REASON_RUN_ERROR                        // 8: The run function returned an error
+	STOP_REASON_GO_ERROR                         // 9: A goroutine started with Go returned an error (see SetStopOnGoError)
+	STOP_REASON_FORCE_TIMEOUT                    // 10: The graceful shutdown time elapsed, so the program was forced to shut down
+	STOP_REASON_FORCE_SHUTDOWN                   // 11: ForceShutdown was called
+)
+
+// defaultExitCodes are the exit codes of the stop reasons, reasons that are not listed exit with 0.
+var defaultExitCodes = map[StopReason]int{
+	STOP_REASON_START_ERROR:    1,
+	STOP_REASON_RUN_ERROR:      1,
+	STOP_REASON_GO_ERROR:       1,
+	STOP_REASON_FORCE_TIMEOUT:  1,
+	STOP_REASON_FORCE_SHUTDOWN: 1,
+}
+
+// String returns the lowercase name of the stop reason.
+func (reason StopReason) String() string {
+	switch reason {
+	case STOP_REASON_NONE:
+		return "none"
+	case STOP_REASON_DONE:
+		return "done"
+	case STOP_REASON_STOP:
+		return "stop"
+	case STOP_REASON_REQUESTED:
+		return "requested"
+	case STOP_REASON_SIGNAL:
+		return "signal"
+	case STOP_REASON_RESTART:
+		return "restart"
+	case STOP_REASON_UPGRADE:
+		return "upgrade"
+	case STOP_REASON_START_ERROR:
+		return "start error"
+	case STOP_REASON_RUN_ERROR:
+		return "run error"
+	case STOP_REASON_GO_ERROR:
+		return "goroutine error"
+	case STOP_REASON_FORCE_TIMEOUT:
+		return "force timeout"
+	case STOP_REASON_FORCE_SHUTDOWN:
+		return "force shutdown"
+	}
+	return "unknown"
+}
+
+// StopError is returned by Start when the service stopped with an error, it tells why the service stopped.
+type StopError struct {
+	Service string     // Name of the service
+	Reason  StopReason // Why the service stopped
+	Err     error      // Errors of the start, run or stop function, goroutines and closers
+}
+
+func (e *StopError) Error() string {
+	return e.Service + " stopped (" + e.Reason.String() + "): " + e.Err.Error()
+}
+
+func (e *StopError) Unwrap() error {
+	return e.Err
+}
+
+// GetStopReason returns why the service stopped, or is stopping. It is reset when the service starts again.
+func (s *Service) GetStopReason() StopReason {
+	return StopReason(s.stopReason.Load())
+}
+
+// setStopReason records why the service stops, unless a reason was already recorded since the service started.
+func (s *Service) setStopReason(reason StopReason) {
+	s.stopReason.CompareAndSwap(int32(STOP_REASON_NONE), int32(reason))
+}
+
+// GetExitCode returns the exit code for the stop reason of the service, to be used with os.Exit when Start has returned.
+// Errors and forced shutdowns exit with 1 and all other reasons with 0, unless changed with SetExitCode.
+func (s *Service) GetExitCode() int {
+	reason := s.GetStopReason()
+	s.mutex.Lock()
+	exitCode, exists := s.exitCodes[reason]
+	s.mutex.Unlock()
+	if !exists {
+		exitCode = defaultExitCodes[reason]
+	}
+	return exitCode
+}
+
+// SetExitCode sets the exit code for a stop reason, which is used by GetExitCode and for forced shutdowns.
+// (for example systemd Restart=on-failure restarts the service on exit codes other than 0)
+func (s *Service) SetExitCode(reason StopReason, exitCode int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.exitCodes == nil {
+		s.exitCodes = map[StopReason]int{}
+	}
+	s.exitCodes[reason] = exitCode
+}
@@ -0,0 +1,73 @@
+package ggservice_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestService_GetStopReason(t *testing.T) {
+	runErr := errors.New("run failed")
+	tests := []struct {
+		name     string
+		runFunc  func(service ggservice.IService) error
+		reason   ggservice.StopReason
+		exitCode int
+	}{
+		{"Stop", func(service ggservice.IService) error { return service.Stop() }, ggservice.STOP_REASON_STOP, 0},
+		{"ErrStopService", func(service ggservice.IService) error { return ggservice.ErrStopService }, ggservice.STOP_REASON_REQUESTED, 0},
+		{"Run error", func(service ggservice.IService) error { return runErr }, ggservice.STOP_REASON_RUN_ERROR, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := newService(t, "My Service")
+			service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+			if service.GetStopReason() != ggservice.STOP_REASON_NONE {
+				t.Errorf("expected no stop reason before start, got %s", service.GetStopReason())
+			}
+
+			err := service.Start(nil, func() error {
+				return test.runFunc(service)
+			}, nil, nil)
+			if service.GetStopReason() != test.reason {
+				t.Errorf("expected stop reason %s, got %s", test.reason, service.GetStopReason())
+			}
+			if service.GetExitCode() != test.exitCode {
+				t.Errorf("expected exit code %d, got %d", test.exitCode, service.GetExitCode())
+			}
+			if test.exitCode == 0 && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	t.Run("StopError", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		startErr := errors.New("start failed")
+		err := service.Start(func() error {
+			return startErr
+		}, nil, nil, nil)
+
+		var stopErr *ggservice.StopError
+		if !errors.As(err, &stopErr) {
+			t.Fatalf("expected StopError, got %v", err)
+		}
+		if stopErr.Reason != ggservice.STOP_REASON_START_ERROR || !errors.Is(err, startErr) {
+			t.Errorf("unexpected stop error: %v", err)
+		}
+	})
+
+	t.Run("SetExitCode", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		service.SetExitCode(ggservice.STOP_REASON_REQUESTED, 4)
+		_ = service.Start(nil, func() error {
+			return ggservice.ErrStopService
+		}, nil, nil)
+		if service.GetExitCode() != 4 {
+			t.Errorf("expected exit code 4, got %d", service.GetExitCode())
+		}
+	})
+}
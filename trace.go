@@ -0,0 +1,238 @@
+package ggservice
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// traceDefaultCapacity is the amount of events a trace recorder keeps if no capacity is given.
+const traceDefaultCapacity = 10000
+
+// Trace span names
+const (
+	TRACE_SPAN_START = "start" // The start function
+	TRACE_SPAN_RUN   = "run"   // An iteration of the run function
+	TRACE_SPAN_STOP  = "stop"  // The stop function
+)
+
+// TraceEvent is an event in the Chrome Trace Event format, which can be opened in Perfetto or chrome://tracing.
+type TraceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat,omitempty"`
+	Phase     string            `json:"ph"`            // "X" for spans, "i" for instant events and "M" for metadata
+	Timestamp int64             `json:"ts"`            // Microseconds since the recorder was created
+	Duration  int64             `json:"dur,omitempty"` // Microseconds, for spans
+	PID       int               `json:"pid"`
+	TID       int               `json:"tid"` // Every service has its own row
+	Scope     string            `json:"s,omitempty"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+// TraceRecorder records the start function, every iteration of the run function, the stop function and the lifecycle phases of services,
+// and writes them as Chrome Trace Event JSON. It keeps the last events in a ring buffer, so its memory is bounded.
+type TraceRecorder struct {
+	events    []TraceEvent // Ring buffer
+	next      int          // Index of the next event in the ring buffer
+	isFull    bool
+	tids      map[string]int // Row of every service
+	createdAt time.Time
+	done      chan struct{}
+	once      sync.Once
+	mutex     sync.Mutex
+}
+
+// NewTraceRecorder creates a new trace recorder that keeps the last capacity events (default: 10000) of the given services.
+func NewTraceRecorder(capacity int, services ...IService) *TraceRecorder {
+	if capacity <= 0 {
+		capacity = traceDefaultCapacity
+	}
+	recorder := &TraceRecorder{
+		events:    make([]TraceEvent, capacity),
+		tids:      map[string]int{},
+		createdAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	for _, service := range services {
+		recorder.Record(service)
+	}
+	return recorder
+}
+
+// Record starts recording the given service.
+func (r *TraceRecorder) Record(service IService) {
+	tracedService, ok := service.(interface{ addTraceRecorder(recorder *TraceRecorder) })
+	if !ok {
+		return // only services of this package can be traced
+	}
+	r.mutex.Lock()
+	r.tid(service.GetName())
+	r.mutex.Unlock()
+	tracedService.addTraceRecorder(r)
+}
+
+// tid returns the row of the service, it must be called with the mutex locked.
+func (r *TraceRecorder) tid(service string) int {
+	tid, exists := r.tids[service]
+	if !exists {
+		tid = len(r.tids) + 1
+		r.tids[service] = tid
+	}
+	return tid
+}
+
+// span records a span of the service, with the error as its outcome.
+func (r *TraceRecorder) span(service string, name string, startTime time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = err.Error()
+	}
+	r.add(service, TraceEvent{
+		Name:      name,
+		Phase:     "X",
+		Timestamp: startTime.Sub(r.createdAt).Microseconds(),
+		Duration:  time.Since(startTime).Microseconds(),
+		Args:      map[string]string{"service": service, "outcome": outcome},
+	})
+}
+
+// instant records a lifecycle event of the service.
+func (r *TraceRecorder) instant(event Event) {
+	args := map[string]string{"service": event.Service}
+	if event.Err != nil {
+		args["error"] = event.Err.Error()
+	}
+	if event.StopReason != STOP_REASON_NONE {
+		args["stopReason"] = event.StopReason.String()
+	}
+	r.add(event.Service, TraceEvent{
+		Name:      event.Phase.String(),
+		Phase:     "i",
+		Timestamp: event.Time.Sub(r.createdAt).Microseconds(),
+		Scope:     "t",
+		Args:      args,
+	})
+}
+
+func (r *TraceRecorder) add(service string, event TraceEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	event.Category = "ggservice"
+	event.PID = os.Getpid()
+	event.TID = r.tid(service)
+	r.events[r.next] = event
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.isFull = true
+	}
+}
+
+// Events returns the recorded events, oldest first.
+func (r *TraceRecorder) Events() []TraceEvent {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.isFull {
+		return append([]TraceEvent(nil), r.events[:r.next]...)
+	}
+	return append(append([]TraceEvent(nil), r.events[r.next:]...), r.events[:r.next]...)
+}
+
+// WriteTo writes the recorded events as Chrome Trace Event JSON, with the names of the services as row names.
+func (r *TraceRecorder) WriteTo(w io.Writer) (int64, error) {
+	events := r.Events()
+	r.mutex.Lock()
+	for service, tid := range r.tids {
+		events = append(events, TraceEvent{
+			Name:  "thread_name",
+			Phase: "M",
+			PID:   os.Getpid(),
+			TID:   tid,
+			Args:  map[string]string{"name": service},
+		})
+	}
+	r.mutex.Unlock()
+
+	data, err := json.Marshal(struct {
+		TraceEvents     []TraceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Dump writes the recorded events as Chrome Trace Event JSON to the given file.
+func (r *TraceRecorder) Dump(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = r.WriteTo(file)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// DumpOnSignal dumps the recorded events to the given file every time the given signal (for example syscall.SIGUSR1) is received,
+// until Close is called. (note: this is a blocking call)
+func (r *TraceRecorder) DumpOnSignal(dumpSignal os.Signal, path string) {
+	osSignal := make(chan os.Signal, 1)
+	signal.Notify(osSignal, dumpSignal)
+	defer signal.Stop(osSignal)
+
+	for {
+		select {
+		case <-osSignal:
+			err := r.Dump(path)
+			if err != nil {
+				log.Println("could not dump trace: " + err.Error())
+				continue
+			}
+			log.Println("dumped trace to " + path)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Close stops dumping on signals.
+func (r *TraceRecorder) Close() error {
+	r.once.Do(func() {
+		close(r.done)
+	})
+	return nil
+}
+
+func (s *Service) addTraceRecorder(recorder *TraceRecorder) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.traceRecorders = append(s.traceRecorders, recorder)
+}
+
+// traceSpan records a span of the service on its trace recorders.
+func (s *Service) traceSpan(name string, startTime time.Time, err error) {
+	s.mutex.Lock()
+	recorders := s.traceRecorders
+	s.mutex.Unlock()
+	for _, recorder := range recorders {
+		recorder.span(s.Name, name, startTime, err)
+	}
+}
+
+// traceEvent records a lifecycle event of the service on its trace recorders.
+func (s *Service) traceEvent(event Event) {
+	s.mutex.Lock()
+	recorders := s.traceRecorders
+	s.mutex.Unlock()
+	for _, recorder := range recorders {
+		recorder.instant(event)
+	}
+}
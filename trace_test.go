@@ -0,0 +1,89 @@
+package ggservice_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestTraceRecorder(t *testing.T) {
+	t.Run("Spans", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		recorder := ggservice.NewTraceRecorder(0, service)
+
+		runs := 0
+		runErr := errors.New("run failed")
+		_ = service.Start(func() error {
+			return nil
+		}, func() error {
+			runs++
+			if runs == 2 {
+				return runErr
+			}
+			return nil
+		}, func() error {
+			return nil
+		}, nil)
+
+		var spans []string
+		for _, event := range recorder.Events() {
+			if event.Phase == "X" {
+				spans = append(spans, event.Name+": "+event.Args["outcome"])
+			}
+		}
+		expected := []string{"start: ok", "run: ok", "run: run failed", "stop: ok"}
+		if len(spans) != len(expected) {
+			t.Fatalf("expected spans %v, got %v", expected, spans)
+		}
+		for i := range expected {
+			if spans[i] != expected[i] {
+				t.Errorf("expected spans %v, got %v", expected, spans)
+				break
+			}
+		}
+
+		var buffer bytes.Buffer
+		_, err := recorder.WriteTo(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var trace struct {
+			TraceEvents []ggservice.TraceEvent `json:"traceEvents"`
+		}
+		err = json.Unmarshal(buffer.Bytes(), &trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := trace.TraceEvents[len(trace.TraceEvents)-1]
+		if last.Phase != "M" || last.Args["name"] != "My Service" {
+			t.Errorf("expected service name as row name, got %+v", last)
+		}
+	})
+
+	t.Run("Ring buffer", func(t *testing.T) {
+		service := newService(t, "My Service")
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+		recorder := ggservice.NewTraceRecorder(3, service)
+
+		runs := 0
+		_ = service.Start(nil, func() error {
+			runs++
+			if runs == 10 {
+				return ggservice.ErrStopService
+			}
+			return nil
+		}, nil, nil)
+
+		events := recorder.Events()
+		if len(events) != 3 {
+			t.Fatalf("expected 3 events, got %d", len(events))
+		}
+		if events[2].Name != "stopped" || events[1].Name != "stopping" || events[0].Name != ggservice.TRACE_SPAN_RUN {
+			t.Errorf("expected the last events, got %+v", events)
+		}
+	})
+}
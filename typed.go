@@ -0,0 +1,75 @@
+package ggservice
+
+import (
+	"context"
+	"time"
+)
+
+// TypedService is a service whose start function creates a state of type S, which is passed to its run and stop functions.
+// This way the functions share state (like connections or counters) without package-level variables.
+// All other methods are those of the wrapped *Service, so a typed service works like the service itself
+// (for example with a Control, Notifier, TraceRecorder or ggservicetest.Interrupt).
+// It is started with StartTyped or StartTypedScheduled instead of Start and StartScheduled.
+type TypedService[S any] struct {
+	*Service
+}
+
+// NewTypedService creates a new typed service with the given name and options. (see NewService)
+func NewTypedService[S any](name string, options ...Option) (*TypedService[S], error) {
+	service, err := NewService(name, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedService[S]{Service: service}, nil
+}
+
+// Typed wraps the service, so it can be started with functions that share a state of type S.
+func Typed[S any](service *Service) *TypedService[S] {
+	return &TypedService[S]{Service: service}
+}
+
+// StartTyped starts the service like IService.Start. The state returned by the start function is passed to the run and stop functions,
+// and it is created again when the service restarts. The stop function also receives the state when the start function failed.
+func (t *TypedService[S]) StartTyped(startFunc func() (S, error), runFunc func(state S) error, stopFunc func(state S) error, forceShutdownFunc func() error) error {
+	var typedRunFunc func(ctx context.Context, state S) (time.Duration, error)
+	if runFunc != nil {
+		typedRunFunc = func(ctx context.Context, state S) (time.Duration, error) {
+			return t.GetRunSleepDuration(), runFunc(state)
+		}
+	}
+	return t.StartTypedScheduled(startFunc, typedRunFunc, stopFunc, forceShutdownFunc)
+}
+
+// StartTypedScheduled starts the service like IService.StartScheduled, with the state of the start function passed to the run and stop functions. (see StartTyped)
+func (t *TypedService[S]) StartTypedScheduled(startFunc func() (S, error), runFunc func(ctx context.Context, state S) (time.Duration, error), stopFunc func(state S) error, forceShutdownFunc func() error) error {
+	// the state of the current start, the functions are called one after another by the same start of the service
+	var state S
+
+	var untypedStartFunc func() error
+	if startFunc != nil {
+		untypedStartFunc = func() error {
+			var err error
+			state, err = startFunc()
+			return err
+		}
+	} else {
+		untypedStartFunc = func() error {
+			var zero S
+			state = zero
+			return nil
+		}
+	}
+	var untypedRunFunc func(ctx context.Context) (time.Duration, error)
+	if runFunc != nil {
+		untypedRunFunc = func(ctx context.Context) (time.Duration, error) {
+			return runFunc(ctx, state)
+		}
+	}
+	var untypedStopFunc func() error
+	if stopFunc != nil {
+		untypedStopFunc = func() error {
+			return stopFunc(state)
+		}
+	}
+	return t.Service.StartScheduled(untypedStartFunc, untypedRunFunc, untypedStopFunc, forceShutdownFunc)
+}
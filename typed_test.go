@@ -0,0 +1,124 @@
+package ggservice_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+// counter is the state of a typed service in the tests.
+type counter struct {
+	generation int
+	runs       int
+}
+
+func TestTypedService(t *testing.T) {
+	t.Run("State", func(t *testing.T) {
+		service, err := ggservice.NewTypedService[*counter]("My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		generations := 0
+		var stopped []counter
+		err = service.StartTyped(func() (*counter, error) {
+			generations++
+			return &counter{generation: generations}, nil
+		}, func(state *counter) error {
+			state.runs++
+			if state.generation == 1 && state.runs == 2 {
+				return ggservice.ErrRestartService // the state is created again
+			}
+			if state.runs == 3 {
+				return ggservice.ErrStopService
+			}
+			return nil
+		}, func(state *counter) error {
+			stopped = append(stopped, *state)
+			return nil
+		}, nil)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := []counter{{generation: 1, runs: 2}, {generation: 2, runs: 3}}
+		if len(stopped) != len(expected) || stopped[0] != expected[0] || stopped[1] != expected[1] {
+			t.Errorf("expected stopped states %v, got %v", expected, stopped)
+		}
+	})
+
+	t.Run("Start error", func(t *testing.T) {
+		service := ggservice.Typed[string](newService(t, "My Service"))
+		service.SetLogLevel(ggservice.LOG_LEVEL_NONE)
+
+		startErr := errors.New("start failed")
+		stoppedState := ""
+		err := service.StartTyped(func() (string, error) {
+			return "partially started", startErr
+		}, nil, func(state string) error {
+			stoppedState = state
+			return nil
+		}, nil)
+		if !errors.Is(err, startErr) {
+			t.Errorf("expected start error, got %v", err)
+		}
+		if stoppedState != "partially started" {
+			t.Errorf("expected stop function to receive the state of the failed start, got %q", stoppedState)
+		}
+	})
+
+	t.Run("Trace and interrupt", func(t *testing.T) {
+		// the typed service has the optional methods of the wrapped service, which are found by type assertions
+		clock := ggservicetest.NewFakeClock(time.Now())
+		service, err := ggservice.NewTypedService[*counter]("My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE), ggservice.WithClock(clock), ggservice.WithRunSleepDuration(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorder := ggservice.NewTraceRecorder(0, service)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- service.StartTyped(func() (*counter, error) {
+				return &counter{}, nil
+			}, func(state *counter) error {
+				state.runs++
+				return nil
+			}, func(state *counter) error {
+				return nil
+			}, func() error {
+				return nil
+			})
+		}()
+		ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		ggservicetest.Interrupt(service)
+		err = <-done
+		if err != nil {
+			t.Error(err)
+		}
+		if service.GetStopReason() != ggservice.STOP_REASON_SIGNAL {
+			t.Errorf("expected stop reason %s, got %s", ggservice.STOP_REASON_SIGNAL, service.GetStopReason())
+		}
+
+		var spans []string
+		for _, event := range recorder.Events() {
+			if event.Phase == "X" {
+				spans = append(spans, event.Name)
+			}
+		}
+		if strings.Join(spans, ",") != "start,run,stop" {
+			t.Errorf("expected the typed service to be traced, got spans %v", spans)
+		}
+	})
+
+	t.Run("IService", func(t *testing.T) {
+		// the typed service keeps the methods of the wrapped service
+		ggservicetest.RunConformance(t, func() ggservice.IService {
+			return ggservice.Typed[string](newService(t, "My Service"))
+		})
+	})
+}
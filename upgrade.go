@@ -0,0 +1,217 @@
+package ggservice
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	upgradeParentPidEnv        = "GGSERVICE_LISTEN_PPID" // pid of the upgrading parent, used instead of LISTEN_PID
+	upgradeReadyFdEnv          = "GGSERVICE_UPGRADE_FD"  // file descriptor the new process reports ready on
+	upgradeDefaultReadyTimeout = 30 * time.Second
+)
+
+var upgradeReady sync.Once
+
+// UpgradeReady tells the parent process that started this process through an upgrade, that it is ready to take over.
+// The parent then stops its services. It does nothing when the process was not started by an upgrade.
+// A Notifier calls UpgradeReady when all its services have started.
+func UpgradeReady() error {
+	var err error
+	upgradeReady.Do(func() {
+		fd, parseErr := strconv.Atoi(os.Getenv(upgradeReadyFdEnv))
+		_ = os.Unsetenv(upgradeReadyFdEnv)
+		if parseErr != nil {
+			return
+		}
+		file := os.NewFile(uintptr(fd), "upgrade")
+		defer file.Close()
+		_, err = file.Write([]byte("ready\n"))
+	})
+	return err
+}
+
+// Upgrader upgrades the program to a new binary without dropping connections.
+// It starts the current executable again, passes all sockets created by Listen and ListenPacket on to it, and waits for the new
+// process to call UpgradeReady. Then the services of this process are shut down gracefully, like on an interrupt signal.
+// If the new process fails to start or does not become ready in time, this process keeps serving.
+type Upgrader struct {
+	services     []IService
+	readyTimeout time.Duration
+	isUpgrading  bool
+	done         chan struct{}
+	once         sync.Once
+	mutex        sync.Mutex
+}
+
+// NewUpgrader creates a new upgrader, which shuts down the given services once the new process is ready.
+func NewUpgrader(services ...IService) *Upgrader {
+	return &Upgrader{
+		services:     services,
+		readyTimeout: upgradeDefaultReadyTimeout,
+		done:         make(chan struct{}),
+	}
+}
+
+func (u *Upgrader) GetReadyTimeout() time.Duration {
+	return u.readyTimeout
+}
+
+// SetReadyTimeout sets for how long Upgrade waits for the new process to become ready. (default: 30 seconds)
+func (u *Upgrader) SetReadyTimeout(readyTimeout time.Duration) {
+	u.readyTimeout = readyTimeout
+}
+
+// ListenForSignal upgrades every time the given signal (for example syscall.SIGUSR2) is received, until Close is called. (note: this is a blocking call)
+func (u *Upgrader) ListenForSignal(upgradeSignal os.Signal) {
+	osSignal := make(chan os.Signal, 1)
+	signal.Notify(osSignal, upgradeSignal)
+	defer signal.Stop(osSignal)
+
+	for {
+		select {
+		case <-osSignal:
+			err := u.Upgrade()
+			if err != nil {
+				log.Println("upgrade failed: " + err.Error())
+			}
+		case <-u.done:
+			return
+		}
+	}
+}
+
+// Close stops listening for the upgrade signal.
+func (u *Upgrader) Close() error {
+	u.once.Do(func() {
+		close(u.done)
+	})
+	return nil
+}
+
+// Upgrade starts the new process and shuts down the services of this process once the new process is ready.
+func (u *Upgrader) Upgrade() error {
+	u.mutex.Lock()
+	if u.isUpgrading {
+		u.mutex.Unlock()
+		return errors.New("an upgrade is already in progress")
+	}
+	u.isUpgrading = true
+	u.mutex.Unlock()
+	defer func() {
+		u.mutex.Lock()
+		u.isUpgrading = false
+		u.mutex.Unlock()
+	}()
+
+	process, err := u.startProcess()
+	if err != nil {
+		return err
+	}
+
+	// the new process is the main process from now on (requires NotifyAccess=all in the systemd unit)
+	_ = SdNotify("MAINPID=" + strconv.Itoa(process.Pid))
+	log.Printf("upgraded to new process (pid %d)\n", process.Pid)
+
+	for _, service := range u.services {
+		if graceful, ok := service.(*Service); ok {
+			graceful.shutdownGracefully(STOP_REASON_UPGRADE, "is upgrading", graceful.getForceShutdownFunc())
+		} else {
+			_ = service.Stop()
+		}
+	}
+	return nil
+}
+
+// startProcess starts the current executable with the inherited sockets and waits for it to be ready.
+func (u *Upgrader) startProcess() (*os.Process, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	files, names := inheritableFiles()
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer readyReader.Close()
+
+	// the sockets are passed like systemd passes them (starting at fd 3), followed by the ready pipe
+	command := exec.Command(executable, os.Args[1:]...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.ExtraFiles = append(append([]*os.File{}, files...), readyWriter)
+	command.Env = append(upgradeEnvironment(),
+		"LISTEN_FDS="+strconv.Itoa(len(files)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+		upgradeParentPidEnv+"="+strconv.Itoa(os.Getpid()),
+		upgradeReadyFdEnv+"="+strconv.Itoa(listenFdsStart+len(files)),
+	)
+	err = command.Start()
+	_ = readyWriter.Close() // only the new process writes to the pipe
+	if err != nil {
+		return nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- command.Wait()
+	}()
+	ready := make(chan error, 1)
+	go func() {
+		line := make([]byte, len("ready\n"))
+		_, err := io.ReadFull(readyReader, line)
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			// the pipe was closed without the new process reporting ready, so it has exited
+			err = <-exited
+			return nil, errors.Join(errors.New("new process exited before it was ready"), err)
+		}
+		select {
+		case err := <-exited:
+			return nil, errors.Join(errors.New("new process exited right after it was ready"), err)
+		default:
+		}
+		return command.Process, nil
+	case err := <-exited:
+		// a new process that exited can not take over, also if it reported ready before exiting
+		return nil, errors.Join(errors.New("new process exited before it took over"), err)
+	case <-time.After(u.readyTimeout):
+		_ = command.Process.Kill()
+		return nil, errors.New("timed out waiting for new process to be ready")
+	}
+}
+
+// upgradeEnvironment returns the environment of this process without the variables describing passed sockets.
+func upgradeEnvironment() []string {
+	var environment []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		switch name {
+		case "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", upgradeParentPidEnv, upgradeReadyFdEnv:
+			continue
+		}
+		environment = append(environment, variable)
+	}
+	return environment
+}
@@ -0,0 +1,138 @@
+package ggservice_test
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+)
+
+func TestUpgrader_Upgrade(t *testing.T) {
+	// the upgrade starts this test binary again with the same arguments, which runs this test as the new process
+	if os.Getenv("GGSERVICE_UPGRADE_FD") != "" {
+		if os.Getenv("GGSERVICE_TEST_EXIT_BEFORE_READY") != "" {
+			return
+		}
+		listener, err := ggservice.Listen("upgrade-http", "tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		if listener.Addr().String() != os.Getenv("GGSERVICE_TEST_ADDRESS") {
+			t.Fatalf("expected inherited listener on %s, got %s", os.Getenv("GGSERVICE_TEST_ADDRESS"), listener.Addr())
+		}
+
+		// the new process keeps serving until it is signalled, like a real program
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM)
+		err = os.WriteFile(os.Getenv("GGSERVICE_TEST_PID_FILE"), []byte(strconv.Itoa(os.Getpid())), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ggservice.UpgradeReady()
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-signals
+		return
+	}
+
+	listener, err := ggservice.Listen("upgrade-http", "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	t.Setenv("GGSERVICE_TEST_ADDRESS", listener.Addr().String())
+	pidFile := filepath.Join(t.TempDir(), "new-process.pid")
+	t.Setenv("GGSERVICE_TEST_PID_FILE", pidFile)
+
+	// the new process only runs this test
+	arguments := os.Args
+	os.Args = []string{os.Args[0], "-test.run=^TestUpgrader_Upgrade$"}
+	defer func() {
+		os.Args = arguments
+	}()
+
+	startService := func() (ggservice.IService, *sync.WaitGroup) {
+		service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE), ggservice.WithGracefulShutdownTime(100*time.Millisecond))
+		waitgroup := &sync.WaitGroup{}
+		waitgroup.Add(1)
+		go func() {
+			defer waitgroup.Done()
+			err := service.Start(nil, func() error {
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			}, nil, func() error {
+				return nil // the test process must not exit
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+		for i := 0; i < 100 && service.GetState() != ggservice.STATE_RUNNING; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return service, waitgroup
+	}
+
+	t.Run("New process exits", func(t *testing.T) {
+		t.Setenv("GGSERVICE_TEST_EXIT_BEFORE_READY", "1")
+		service, waitgroup := startService()
+
+		upgrader := ggservice.NewUpgrader(service)
+		upgrader.SetReadyTimeout(30 * time.Second)
+		err := upgrader.Upgrade()
+		if err == nil || !strings.Contains(err.Error(), "exited") {
+			t.Errorf("expected the upgrade to fail, got %v", err)
+		}
+		if service.GetState() != ggservice.STATE_RUNNING {
+			t.Errorf("expected this process to keep serving, got %s", service.GetState())
+		}
+
+		err = service.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		waitgroup.Wait()
+	})
+
+	t.Run("New process takes over", func(t *testing.T) {
+		service, waitgroup := startService()
+
+		upgrader := ggservice.NewUpgrader(service)
+		upgrader.SetReadyTimeout(30 * time.Second)
+		err := upgrader.Upgrade()
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitgroup.Wait()
+		if service.GetState() != ggservice.STATE_STOPPED {
+			t.Errorf("expected service to be stopped after upgrade, got %s", service.GetState())
+		}
+
+		// stop the new process, which is still serving
+		content, err := os.ReadFile(pidFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pid, err := strconv.Atoi(string(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = process.Signal(syscall.SIGTERM)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package ggservice
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	watcherDefaultPollInterval = time.Second            // how often the watched files are checked for changes
+	watcherDefaultDebounce     = 500 * time.Millisecond // how long a file has to stay unchanged before the services are reloaded
+)
+
+// ConfigWatcher reloads services when their config files change. It polls the modification time and size of the files,
+// and compares a hash of their content when those change, so it does not depend on OS-specific file notifications.
+// A burst of writes reloads the services once, after the files have stayed unchanged for the debounce duration.
+type ConfigWatcher struct {
+	files        map[string]*watchedFile
+	pollInterval time.Duration
+	debounce     time.Duration
+	clock        Clock
+	done         chan struct{}
+	once         sync.Once
+	mutex        sync.Mutex
+}
+
+// watchedFile is a config file watched by a ConfigWatcher, with the services reloaded when it changes.
+type watchedFile struct {
+	targets   []*watchTarget
+	isKnown   bool // whether the file has been read once, changes are only detected from then on
+	modTime   time.Time
+	size      int64
+	hash      [sha256.Size]byte
+	changedAt time.Time // when a change was detected last, the services are reloaded once it is longer than the debounce duration ago
+	isChanged bool
+}
+
+// watchTarget is a service reloaded when a watched file changes, with its reload function (nil to use Reload of the service).
+type watchTarget struct {
+	service    IService
+	reloadFunc func() error
+}
+
+// NewConfigWatcher creates a new config watcher without files, add them with Watch.
+func NewConfigWatcher() *ConfigWatcher {
+	return &ConfigWatcher{
+		files:        map[string]*watchedFile{},
+		pollInterval: watcherDefaultPollInterval,
+		debounce:     watcherDefaultDebounce,
+		done:         make(chan struct{}),
+	}
+}
+
+// Watch reloads the service when the file at the given path changes, by calling the reload function. If the reload function is nil,
+// Reload of the service is called if it has one, which reloads a *Service started with a Runnable that implements Reloader.
+// If the reload function (or Reload) returns ErrNotSupported, or the service has no Reload, the service is restarted instead. Services that are not running are not reloaded.
+// A file can be watched for several services, and a service for several files. When they change together, Reload of the service is called once,
+// and every reload function is called once for each Watch call it was passed to (so different reload functions of a service are all called).
+// A file that does not exist (yet) is watched from when it is created, and a file that is removed is not a change.
+func (w *ConfigWatcher) Watch(path string, service IService, reloadFunc func() error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	file, ok := w.files[path]
+	if !ok {
+		file = &watchedFile{}
+		w.files[path] = file
+	}
+	file.targets = append(file.targets, &watchTarget{service: service, reloadFunc: reloadFunc})
+}
+
+func (w *ConfigWatcher) GetPollInterval() time.Duration {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.pollInterval
+}
+
+// SetPollInterval sets how often the watched files are checked for changes. (default: 1 second)
+func (w *ConfigWatcher) SetPollInterval(pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		return fmt.Errorf("poll interval must be positive: %v", pollInterval)
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pollInterval = pollInterval
+	return nil
+}
+
+func (w *ConfigWatcher) GetDebounce() time.Duration {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.debounce
+}
+
+// SetDebounce sets for how long a changed file has to stay unchanged before its services are reloaded. (default: 500 milliseconds)
+// As the files are polled, the services are reloaded at the first poll after the debounce duration.
+func (w *ConfigWatcher) SetDebounce(debounce time.Duration) error {
+	if debounce < 0 {
+		return fmt.Errorf("negative debounce: %v", debounce)
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.debounce = debounce
+	return nil
+}
+
+func (w *ConfigWatcher) GetClock() Clock {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.clock == nil {
+		return realClock{}
+	}
+	return w.clock
+}
+
+// SetClock sets the clock the poll interval and the debounce duration are measured with, nil for the clock of the time package. (default: nil)
+func (w *ConfigWatcher) SetClock(clock Clock) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.clock = clock
+}
+
+// Run watches the files until Close is called. (note: this is a blocking call)
+// The files are read when Run starts, so only later changes reload the services.
+func (w *ConfigWatcher) Run() {
+	clock := w.GetClock()
+	for {
+		for _, target := range w.poll(clock.Now()) {
+			w.reload(target)
+		}
+
+		timer := clock.NewTimer(w.GetPollInterval())
+		select {
+		case <-timer.C():
+		case <-w.done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Close stops watching the files.
+func (w *ConfigWatcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	return nil
+}
+
+// poll checks the files for changes, and returns the services to reload for the files that stayed unchanged for the debounce duration.
+// Targets without reload function are reloaded once per service, targets with a reload function once per Watch call.
+func (w *ConfigWatcher) poll(now time.Time) []*watchTarget {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var targets []*watchTarget
+	isTarget := map[any]bool{}
+	for path, file := range w.files {
+		file.check(path, now)
+		if !file.isChanged || now.Sub(file.changedAt) < w.debounce {
+			continue
+		}
+		file.isChanged = false
+		for _, target := range file.targets {
+			var key any = target
+			if target.reloadFunc == nil {
+				key = target.service
+			}
+			if !isTarget[key] {
+				isTarget[key] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// check reads the file if its modification time or size changed, and marks it as changed if its content changed.
+// A file that can not be read keeps its last known state, so a file that is replaced is only changed once it was written.
+func (f *watchedFile) check(path string, now time.Time) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if f.isKnown && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	hash := sha256.Sum256(data)
+	if f.isKnown && hash != f.hash {
+		f.changedAt = now
+		f.isChanged = true
+	}
+	f.isKnown = true
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	f.hash = hash
+}
+
+// reload reloads the running service with the reload function of the target, and restarts it if it can not reload.
+func (w *ConfigWatcher) reload(target *watchTarget) {
+	service := target.service
+	if !service.GetIsRunning() {
+		return
+	}
+	if service.GetLogLevel() >= LOG_LEVEL_INFO {
+		log.Println("Config changed, reloading service: " + service.GetName())
+	}
+
+	var err error
+	if target.reloadFunc != nil {
+		err = target.reloadFunc()
+	} else if reloader, ok := service.(Reloader); ok {
+		err = reloader.Reload()
+	} else {
+		err = fmt.Errorf("reload %s: %w", service.GetName(), ErrNotSupported)
+	}
+	if errors.Is(err, ErrNotSupported) {
+		if service.GetLogLevel() >= LOG_LEVEL_INFO {
+			log.Println("Service can not reload, restarting service: " + service.GetName())
+		}
+		// Restart blocks for as long as the restarted service runs
+		go func() {
+			err := service.Restart()
+			if err != nil && service.GetLogLevel() >= LOG_LEVEL_WARN {
+				log.Printf("restart of %s failed: %v\n", service.GetName(), err)
+			}
+		}()
+		return
+	}
+	if err != nil && service.GetLogLevel() >= LOG_LEVEL_WARN {
+		log.Printf("reload of %s failed: %v\n", service.GetName(), err)
+	}
+}
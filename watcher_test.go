@@ -0,0 +1,218 @@
+package ggservice_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lmbek/ggservice"
+	"github.com/lmbek/ggservice/ggservicetest"
+)
+
+// writeConfig writes the config file, and fails the test if it can not be written.
+func writeConfig(t *testing.T, path string, content string) {
+	t.Helper()
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigWatcher(t *testing.T) {
+	t.Run("Debounced reload", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeConfig(t, path, `{"workers": 1}`)
+
+		calls := make(chan string, 10)
+		service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE))
+		done := make(chan error, 1)
+		go func() {
+			done <- ggservice.StartRunnable(service, &reloadingWorker{calls: calls})
+		}()
+		if call := <-calls; call != "start" {
+			t.Fatalf("expected start, got %s", call)
+		}
+		ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, time.Second)
+
+		clock := ggservicetest.NewFakeClock(time.Unix(0, 0))
+		watcher := ggservice.NewConfigWatcher()
+		watcher.SetClock(clock)
+		if watcher.SetPollInterval(time.Second) != nil || watcher.SetDebounce(3*time.Second) != nil {
+			t.Fatal("expected valid poll interval and debounce")
+		}
+		watcher.Watch(path, service, nil)
+		go watcher.Run()
+		defer watcher.Close()
+		clock.AwaitTimers(t, 1, time.Second) // the file was read
+
+		// a burst of writes, the last one two seconds after the first
+		writeConfig(t, path, `{"workers": 2}`)
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		writeConfig(t, path, `{"workers": 10}`)
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		if len(calls) != 0 {
+			t.Fatalf("expected no reload before the file stayed unchanged for the debounce duration, got %s", <-calls)
+		}
+
+		clock.Advance(2 * time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		if call := <-calls; call != "reload" {
+			t.Errorf("expected reload, got %s", call)
+		}
+
+		// writing the same content is not a change
+		writeConfig(t, path, `{"workers": 10}`)
+		clock.Advance(5 * time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		clock.Advance(5 * time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		if len(calls) != 0 {
+			t.Errorf("expected no reload for unchanged content, got %s", <-calls)
+		}
+
+		err := service.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		err = <-done
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Restart if reload is not supported", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeConfig(t, path, `{"workers": 1}`)
+
+		starts := make(chan struct{}, 10)
+		service := newService(t, "My Service", ggservice.WithLogLevel(ggservice.LOG_LEVEL_NONE))
+		go service.Start(func() error {
+			starts <- struct{}{}
+			return nil
+		}, func() error {
+			return nil
+		}, nil, nil)
+		<-starts
+		ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, time.Second)
+
+		clock := ggservicetest.NewFakeClock(time.Unix(0, 0))
+		watcher := ggservice.NewConfigWatcher()
+		watcher.SetClock(clock)
+		watcher.Watch(path, service, nil)
+		go watcher.Run()
+		defer watcher.Close()
+		clock.AwaitTimers(t, 1, time.Second)
+
+		writeConfig(t, path, `{"workers": 2}`)
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+
+		select {
+		case <-starts:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the service to be restarted")
+		}
+		ggservicetest.AwaitState(t, service, ggservice.STATE_RUNNING, time.Second)
+		err := service.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Restart without Reload", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeConfig(t, path, `{"workers": 1}`)
+
+		fake := ggservicetest.NewFakeService("My Service")
+		fake.Return("GetIsRunning", true)
+		service := struct{ ggservice.IService }{fake} // a decorator without Reload
+
+		clock := ggservicetest.NewFakeClock(time.Unix(0, 0))
+		watcher := ggservice.NewConfigWatcher()
+		watcher.SetClock(clock)
+		watcher.Watch(path, service, nil)
+		go watcher.Run()
+		defer watcher.Close()
+		clock.AwaitTimers(t, 1, time.Second)
+
+		writeConfig(t, path, `{"workers": 2}`)
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+
+		for i := 0; !slices.Contains(fake.Methods(), "Restart"); i++ {
+			if i == 500 {
+				t.Fatalf("expected the service to be restarted, got calls %v", fake.Methods())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("Several files", func(t *testing.T) {
+		dir := t.TempDir()
+		paths := []string{filepath.Join(dir, "config.json"), filepath.Join(dir, "secrets.json")}
+		for _, path := range paths {
+			writeConfig(t, path, `{}`)
+		}
+
+		fake := ggservicetest.NewFakeService("My Service")
+		fake.Return("GetIsRunning", true)
+		calls := make(chan string, 10)
+		reloadFunc := func(name string) func() error {
+			return func() error {
+				calls <- name
+				return nil
+			}
+		}
+
+		clock := ggservicetest.NewFakeClock(time.Unix(0, 0))
+		watcher := ggservice.NewConfigWatcher()
+		watcher.SetClock(clock)
+		watcher.Watch(paths[0], fake, reloadFunc("config"))
+		watcher.Watch(paths[1], fake, reloadFunc("secrets"))
+		go watcher.Run()
+		defer watcher.Close()
+		clock.AwaitTimers(t, 1, time.Second)
+
+		for _, path := range paths {
+			writeConfig(t, path, `{"workers": 2}`)
+		}
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+		clock.Advance(time.Second)
+		clock.AwaitTimers(t, 1, time.Second)
+
+		var reloaded []string
+		for len(reloaded) < 2 {
+			select {
+			case call := <-calls:
+				reloaded = append(reloaded, call)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected both reload functions to be called, got %v", reloaded)
+			}
+		}
+		slices.Sort(reloaded)
+		if !slices.Equal(reloaded, []string{"config", "secrets"}) {
+			t.Errorf("expected config and secrets to be reloaded, got %v", reloaded)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		watcher := ggservice.NewConfigWatcher()
+		if watcher.SetPollInterval(0) == nil || watcher.SetDebounce(-time.Second) == nil {
+			t.Error("expected error")
+		}
+		if watcher.GetPollInterval() != time.Second || watcher.GetDebounce() != 500*time.Millisecond {
+			t.Error("expected invalid values to be rejected")
+		}
+	})
+}